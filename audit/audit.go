@@ -0,0 +1,189 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package audit records security relevant actions, e.g. config changes, admin calls
+// and deletions, apart from the app logs.
+//
+// Usage:
+//
+//	audit.Record(ctx, audit.Event{Action: "blob.delete", Target: key, Outcome: audit.Outcome(err)})
+//
+// Setup env JSON value:
+//
+//	goboot_audit={
+//	  "store": "postgres",
+//	  "table": "audit_events"
+//	}
+//
+// Stores: file appends JSON lines to path, postgres inserts into table through
+// postgres.DB(). The records are hash chained, see Verify and "goboot audit verify".
+// They never go through the app loggers, whose sampling and levels would drop some.
+// Without a store the events are discarded, with a warning on the first one.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var settings = config.AppSettings()
+var log = logging.Named("audit")
+
+// SetLogger makes the package report store errors to l instead of the "audit" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
+
+type AuditEnv struct {
+	Store string `env:"goboot_audit.store" desc:"file, postgres or a type registered with RegisterStore, none if not set"`
+	Path  string `env:"goboot_audit.path" desc:"file of the file store, required by it"`
+	Table string `env:"goboot_audit.table" envDefault:"audit_events" desc:"table of the postgres store, created if missing"`
+}
+
+// the store of Record, opened on first use as the stores of other packages register at init
+var std = struct {
+	store Store
+	sync.Mutex
+}{}
+
+func init() {
+	config.Declare(AuditEnv{}, "Audit trail")
+
+	settings.Subscribe("goboot_audit", func(old, new interface{}) {
+		SetStore(nil)
+	})
+
+	logging.SetAuditor(func(ctx context.Context, action, target string, err error, details map[string]string) {
+		e := Event{Action: action, Target: target, Outcome: Outcome(err), Details: map[string]string{}}
+		for k, v := range details {
+			e.Details[k] = v
+		}
+		if err != nil {
+			e.Details["error"] = err.Error()
+		}
+		Record(ctx, e)
+	})
+
+	settings.OnReload(func(changed []string, err error) {
+		if len(changed) == 0 && err == nil {
+			return
+		}
+		e := Event{Action: "config.reload", Target: strings.Join(changed, ","), Outcome: Outcome(err)}
+		if err != nil {
+			e.Details = map[string]string{"error": err.Error()}
+		}
+		Record(context.Background(), e)
+	})
+}
+
+// SetStore replaces the store of Record, closing the current one. With nil, the
+// store of goboot_audit is opened on the next Record.
+func SetStore(s Store) {
+	std.Lock()
+	old := std.store
+	std.store = s
+	std.Unlock()
+
+	if old != nil && old != s {
+		old.Close()
+	}
+}
+
+func store() (Store, error) {
+	std.Lock()
+	defer std.Unlock()
+
+	if std.store != nil {
+		return std.store, nil
+	}
+	env := AuditEnv{}
+	if err := settings.Parse(&env); err != nil {
+		return nil, err
+	}
+	if env.Store == "" {
+		log.Warn("Audit trail disabled, set goboot_audit.store to record the events")
+		std.store = discard{}
+		return std.store, nil
+	}
+	s, err := OpenStore(env)
+	if err != nil {
+		return nil, err
+	}
+	std.store = s
+	return s, nil
+}
+
+// Record appends e to the audit store. The actor defaults to the one of ctx, see
+// NewContext, and the request ID to the one of the logger of ctx. The details are
+// masked like log entries. If the store fails, the event is written to stderr
+// and the error returned.
+func Record(ctx context.Context, e Event) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if e.Actor == "" {
+		e.Actor = Actor(ctx)
+	}
+	if e.RequestID == "" {
+		e.RequestID = logging.RequestID(ctx)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	e.Details = redact(e.Details)
+
+	s, err := store()
+	if err == nil {
+		err = s.Append(&e)
+	}
+	if err != nil {
+		log.Errorf("Audit store error: %v", err)
+		b, _ := json.Marshal(e)
+		fmt.Fprintf(os.Stderr, "goboot: audit event not stored: %s\n", b)
+	}
+	return err
+}
+
+// discard is the store of Record if goboot_audit.store is not set.
+type discard struct{}
+
+func (r discard) Append(e *Event) error              { return nil }
+func (r discard) Read(fn func(e *Event) error) error { return nil }
+func (r discard) Close() error                       { return nil }
+
+// Outcome returns OutcomeFailure if err is set, OutcomeSuccess otherwise.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+func redact(details map[string]string) map[string]string {
+	if len(details) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(details))
+	for k, v := range details {
+		m[k] = v
+	}
+	masked, _ := logging.Redact(m).(map[string]interface{})
+	out := make(map[string]string, len(masked))
+	for k, v := range masked {
+		out[k] = fmt.Sprint(v)
+	}
+	return out
+}
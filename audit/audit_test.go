@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/gostones/goboot/logtest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "goboot-audit")
+	assert.NoError(t, err)
+	return filepath.Join(dir, "audit.log"), func() { os.RemoveAll(dir) }
+}
+
+func TestRecord(t *testing.T) {
+	path, cleanup := tempFile(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	assert.NoError(t, err)
+	SetStore(s)
+	defer SetStore(nil)
+
+	ctx := logging.NewContext(context.Background(), logging.Logger().WithField(logging.FieldRequestID, "req-1"))
+	ctx = NewContext(ctx, "alice@192.0.2.1")
+	assert.NoError(t, Record(ctx, Event{Action: "blob.delete", Target: "a.txt"}))
+	assert.NoError(t, Record(nil, Event{Action: "config.reload", Target: "goboot_x", Outcome: OutcomeFailure,
+		Details: map[string]string{"password": "hunter22"}}))
+
+	var events []*Event
+	assert.NoError(t, s.Read(func(e *Event) error {
+		events = append(events, e)
+		return nil
+	}))
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(1), events[0].Seq)
+	assert.Equal(t, "alice@192.0.2.1", events[0].Actor)
+	assert.Equal(t, "req-1", events[0].RequestID)
+	assert.Equal(t, OutcomeSuccess, events[0].Outcome)
+	assert.Equal(t, "", events[0].PrevHash)
+	assert.Equal(t, events[0].Hash, events[1].PrevHash)
+	assert.Equal(t, ActorSystem, events[1].Actor)
+	assert.NotEqual(t, "hunter22", events[1].Details["password"])
+
+	// reopening continues the chain
+	s.Close()
+	s, err = NewFileStore(path)
+	assert.NoError(t, err)
+	SetStore(s)
+	assert.NoError(t, Record(ctx, Event{Action: "redis.delete", Target: "k"}))
+
+	report, err := Verify(s)
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 3, report.Records)
+	assert.Equal(t, int64(3), report.Last.Seq)
+}
+
+func TestVerify(t *testing.T) {
+	path, cleanup := tempFile(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	assert.NoError(t, err)
+	for _, target := range []string{"a", "b", "c", "d"} {
+		e := Event{Action: "blob.delete", Target: target, Outcome: OutcomeSuccess}
+		assert.NoError(t, s.Append(&e))
+	}
+	s.Close()
+
+	b, _ := ioutil.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	// modify b, remove c
+	lines[1] = strings.Replace(lines[1], `"target":"b"`, `"target":"x"`, 1)
+	lines = append(lines[:2], lines[3])
+	assert.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+
+	s, err = NewFileStore(path)
+	assert.NoError(t, err)
+	defer s.Close()
+	report, err := Verify(s)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Records)
+	assert.Equal(t, []Problem{
+		{Seq: 2, Reason: "hash mismatch, the record was modified"},
+		{Seq: 4, Reason: "record 3 missing"},
+	}, report.Problems)
+}
+
+// brokenStore fails every append.
+type brokenStore struct{}
+
+func (r brokenStore) Append(e *Event) error              { return errors.New("disk full") }
+func (r brokenStore) Read(fn func(e *Event) error) error { return nil }
+func (r brokenStore) Close() error                       { return nil }
+
+func TestStoreError(t *testing.T) {
+	l, rec := logtest.New(t)
+	defer l.Close()
+	SetLogger(l.Entry)
+	defer SetLogger(logging.Named("audit"))
+
+	SetStore(brokenStore{})
+	defer SetStore(nil)
+
+	err := Record(context.Background(), Event{Action: "admin.call", Target: "/admin/logging", Outcome: OutcomeFailure})
+	assert.EqualError(t, err, "disk full")
+	rec.AssertLogged(logrus.ErrorLevel, "Audit store error: disk full", nil)
+	rec.AssertNotLogged(logrus.InfoLevel, "admin.call", nil)
+
+	_, err = OpenStore(AuditEnv{Store: "tape"})
+	assert.Error(t, err)
+	_, err = OpenStore(AuditEnv{Store: StoreFile})
+	assert.EqualError(t, err, "goboot_audit.path not set")
+}
+
+func TestNoStore(t *testing.T) {
+	l, rec := logtest.New(t)
+	defer l.Close()
+	SetLogger(l.Entry)
+	defer SetLogger(logging.Named("audit"))
+
+	SetStore(nil)
+	assert.NoError(t, Record(context.Background(), Event{Action: "blob.delete", Target: "a"}))
+	assert.NoError(t, Record(context.Background(), Event{Action: "blob.delete", Target: "b"}))
+	assert.Len(t, rec.Find(logrus.WarnLevel, "Audit trail disabled, set goboot_audit.store to record the events", nil), 1)
+
+	_, err := os.Stat("audit.log")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Outcomes of an action.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// ActorSystem is the actor of actions not requested by a user, e.g. a config reload.
+const ActorSystem = "system"
+
+// Event is a security relevant action. Seq, PrevHash and Hash are set when the event is
+// appended: each record carries the hash of the previous one, so removing or changing
+// a record breaks the chain, see Verify.
+type Event struct {
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	Target    string            `json:"target"`
+	Outcome   string            `json:"outcome"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// Chain links e to prev, the last record of the store or nil if there is none,
+// and sets its hash. Stores call it while holding their append lock.
+func (r *Event) Chain(prev *Event) {
+	r.Seq = 1
+	r.PrevHash = ""
+	if prev != nil {
+		r.Seq = prev.Seq + 1
+		r.PrevHash = prev.Hash
+	}
+	// the precision and zone stores keep, e.g. a Postgres timestamptz
+	r.Time = r.Time.UTC().Truncate(time.Microsecond)
+	r.Hash = r.ComputeHash()
+}
+
+// ComputeHash returns the hex SHA-256 of the JSON form of the event without its hash.
+func (r Event) ComputeHash() string {
+	r.Hash = ""
+	r.Time = r.Time.UTC()
+	b, _ := json.Marshal(r)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey int
+
+const actorKey contextKey = iota
+
+// NewContext returns a context carrying the actor of the actions done with it,
+// e.g. the user of a request.
+func NewContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the actor of ctx, ActorSystem if there is none.
+func Actor(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return ActorSystem
+}
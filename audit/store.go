@@ -0,0 +1,140 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StoreFile is the store type built in, others are added with RegisterStore,
+// e.g. the postgres package adds "postgres".
+const StoreFile = "file"
+
+// Store keeps the records append only.
+type Store interface {
+	// Append chains e to the last record, see Event.Chain, and writes it.
+	// Appends must be serialized across the writers of the store.
+	Append(e *Event) error
+	// Read calls fn with the records in the order they were appended until fn returns an error.
+	Read(fn func(e *Event) error) error
+	Close() error
+}
+
+// StoreFactory opens a store configured by env.
+type StoreFactory func(env AuditEnv) (Store, error)
+
+var factories = struct {
+	m map[string]StoreFactory
+	sync.RWMutex
+}{m: map[string]StoreFactory{
+	StoreFile: func(env AuditEnv) (Store, error) {
+		if env.Path == "" {
+			return nil, errors.New("goboot_audit.path not set")
+		}
+		return NewFileStore(env.Path)
+	},
+}}
+
+// RegisterStore adds a store type.
+func RegisterStore(name string, factory StoreFactory) {
+	factories.Lock()
+	defer factories.Unlock()
+	factories.m[name] = factory
+}
+
+// OpenStore opens the store of env.Store.
+func OpenStore(env AuditEnv) (Store, error) {
+	factories.RLock()
+	factory, ok := factories.m[env.Store]
+	factories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown audit store: %q", env.Store)
+	}
+	return factory(env)
+}
+
+// fileStore appends a JSON record per line. A single process may write the file.
+type fileStore struct {
+	path string
+	f    *os.File
+	last *Event
+
+	sync.Mutex
+}
+
+// NewFileStore opens the file at path, creating it if needed, and reads its last record.
+func NewFileStore(path string) (Store, error) {
+	if path == "" {
+		return nil, errors.New("audit file path not set")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := &fileStore{path: path, f: f}
+	err = r.Read(func(e *Event) error {
+		r.last = e
+		return nil
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileStore) Append(e *Event) error {
+	r.Lock()
+	defer r.Unlock()
+
+	e.Chain(r.last)
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := r.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := r.f.Sync(); err != nil {
+		return err
+	}
+	last := *e
+	r.last = &last
+	return nil
+}
+
+func (r *fileStore) Read(fn func(e *Event) error) error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		e := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return fmt.Errorf("%s:%d: %v", r.path, n, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (r *fileStore) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import "fmt"
+
+// Problem is a record failing verification.
+type Problem struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+func (r Problem) String() string {
+	return fmt.Sprintf("record %d: %s", r.Seq, r.Reason)
+}
+
+// Report is the result of Verify. Keep Last elsewhere, e.g. in a ticket, to detect
+// records removed from the end later: the chain can't tell.
+type Report struct {
+	Records  int       `json:"records"`
+	Last     *Event    `json:"last,omitempty"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// OK reports whether no problem was found.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify reads the records of s and reports gaps in the sequence, records not linked
+// to the previous one and records whose hash doesn't match their content.
+func Verify(s Store) (*Report, error) {
+	report := &Report{}
+	var prev *Event
+	err := s.Read(func(e *Event) error {
+		report.Records++
+
+		expected := int64(1)
+		prevHash := ""
+		if prev != nil {
+			expected = prev.Seq + 1
+			prevHash = prev.Hash
+		}
+		switch {
+		case e.Seq == expected+1:
+			report.add(e.Seq, fmt.Sprintf("record %d missing", expected))
+		case e.Seq > expected:
+			report.add(e.Seq, fmt.Sprintf("records %d to %d missing", expected, e.Seq-1))
+		case e.Seq < expected:
+			report.add(e.Seq, fmt.Sprintf("out of sequence, expected %d", expected))
+		case e.PrevHash != prevHash:
+			report.add(e.Seq, "not chained to the previous record")
+		}
+		if e.Hash != e.ComputeHash() {
+			report.add(e.Seq, "hash mismatch, the record was modified")
+		}
+
+		prev = e
+		return nil
+	})
+	report.Last = prev
+	return report, err
+}
+
+func (r *Report) add(seq int64, reason string) {
+	r.Problems = append(r.Problems, Problem{Seq: seq, Reason: reason})
+}
@@ -14,19 +14,34 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/cloudfoundry-community/go-cfenv"
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
 )
 
 var settings = config.AppSettings()
-var log = logging.Logger()
+var log = logging.Named("cf/blobstore")
+
+// SetLogger makes the package log to l instead of the "cf/blobstore" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 type BlobstoreEnv struct {
-	Name string `env:"goboot_blobstore.name"`
+	Name string `env:"goboot_blobstore.name" desc:"name of the bound blobstore service, the predix-blobstore label is tried otherwise"`
+}
+
+// BlobstoreCredentials are the credentials of the bound blobstore service
+type BlobstoreCredentials struct {
+	AccessKeyID     string `cred:"access_key_id,required"`
+	SecretAccessKey string `cred:"secret_access_key,required"`
+	Host            string `cred:"host,required"`
+	BucketName      string `cred:"bucket_name,required"`
 }
 
 func init() {
+	config.Declare(BlobstoreEnv{}, "Blobstore (S3) bucket")
+
 	env := BlobstoreEnv{}
 
 	err := settings.Parse(&env)
@@ -52,15 +67,17 @@ func initStore(env BlobstoreEnv) {
 	disableSSL := true
 	logLevel := aws.LogDebugWithRequestErrors
 
-	s := settings.GetService(env.Name, "predix-blobstore").(cfenv.Service)
-	accessKeyID := s.Credentials["access_key_id"].(string)
-	secretAccessKey := s.Credentials["secret_access_key"].(string)
-	endpoint := s.Credentials["host"].(string)
+	creds := BlobstoreCredentials{}
+	if err := settings.BindService(&creds, env.Name, "predix-blobstore"); err != nil {
+		log.Errorf("Blobstore init error: %v", err)
+		return
+	}
+	endpoint := creds.Host
 
-	BucketName = s.Credentials["bucket_name"].(string)
+	BucketName = creds.BucketName
 
 	Config = aws.Config{
-		Credentials: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
+		Credentials: credentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, ""),
 		Region:      &region,
 		Endpoint:    &endpoint,
 		DisableSSL:  &disableSSL,
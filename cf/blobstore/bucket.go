@@ -1,8 +1,10 @@
 package blobstore
 
 import (
+	"context"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gostones/goboot/audit"
 	"io"
 )
 
@@ -59,12 +61,28 @@ func Get(key string) (r *s3.GetObjectOutput, err error) {
 	return
 }
 
+// Delete deletes the blob of key, see DeleteContext.
 func Delete(key string) (r *s3.DeleteObjectOutput, err error) {
+	return DeleteContext(context.Background(), key)
+}
+
+// DeleteContext deletes the blob of key and records it to the audit trail
+// with the actor and the request ID of ctx.
+func DeleteContext(ctx context.Context, key string) (r *s3.DeleteObjectOutput, err error) {
 	params := &s3.DeleteObjectInput{
 		Bucket: &BucketName,
 		Key:    &key,
 	}
 
-	r, err = S3.DeleteObject(params)
+	r, err = S3.DeleteObjectWithContext(ctx, params)
+	auditDelete(ctx, "blob.delete", BucketName+"/"+key, err)
 	return
 }
+
+func auditDelete(ctx context.Context, action, target string, err error) {
+	e := audit.Event{Action: action, Target: target, Outcome: audit.Outcome(err)}
+	if err != nil {
+		e.Details = map[string]string{"error": err.Error()}
+	}
+	audit.Record(ctx, e)
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/gostones/goboot/audit"
+)
+
+// AuditStoreType is the audit store type registered by this package, see audit.RegisterStore.
+const AuditStoreType = "postgres"
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// auditStore inserts audit records into a table, locking it while chaining a record
+// so that the instances of an app share one chain.
+type auditStore struct {
+	db    *sql.DB
+	table string
+}
+
+func init() {
+	audit.RegisterStore(AuditStoreType, func(env audit.AuditEnv) (audit.Store, error) {
+		if DB() == nil {
+			return nil, errors.New("postgres not initialized")
+		}
+		return NewAuditStore(DB(), env.Table)
+	})
+}
+
+// NewAuditStore returns an audit store writing to table, created if it doesn't exist.
+func NewAuditStore(db *sql.DB, table string) (audit.Store, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name: %q", table)
+	}
+	_, err := db.Exec(`create table if not exists ` + table + ` (
+		seq bigint primary key,
+		time timestamptz not null,
+		actor text not null,
+		action text not null,
+		target text not null,
+		outcome text not null,
+		request_id text not null,
+		details text not null,
+		prev_hash text not null,
+		hash text not null
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &auditStore{db: db, table: table}, nil
+}
+
+func (r *auditStore) Append(e *audit.Event) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// readers are not blocked, other writers wait for the commit
+	if _, err := tx.Exec(`lock table ` + r.table + ` in exclusive mode`); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`select ` + auditColumns + ` from ` + r.table + ` order by seq desc limit 1`)
+	if err != nil {
+		return err
+	}
+	var prev *audit.Event
+	if rows.Next() {
+		prev, err = scanEvent(rows)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	e.Chain(prev)
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`insert into `+r.table+` (`+auditColumns+`) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		e.Seq, e.Time, e.Actor, e.Action, e.Target, e.Outcome, e.RequestID, string(details), e.PrevHash, e.Hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *auditStore) Read(fn func(e *audit.Event) error) error {
+	rows, err := r.db.Query(`select ` + auditColumns + ` from ` + r.table + ` order by seq`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close keeps the database open, it is shared.
+func (r *auditStore) Close() error {
+	return nil
+}
+
+const auditColumns = `seq, time, actor, action, target, outcome, request_id, details, prev_hash, hash`
+
+func scanEvent(rows *sql.Rows) (*audit.Event, error) {
+	e := &audit.Event{}
+	var details string
+	err := rows.Scan(&e.Seq, &e.Time, &e.Actor, &e.Action, &e.Target, &e.Outcome, &e.RequestID, &details, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	e.Time = e.Time.UTC()
+	if err := json.Unmarshal([]byte(details), &e.Details); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gostones/goboot/logging"
+)
+
+// ErrNoDB is returned by the context helpers when no database is bound.
+var ErrNoDB = errors.New("postgres: no database")
+
+// StatusContext checks the database like Status, logging with the request id carried by ctx,
+// see logging.WithContext.
+func StatusContext(ctx context.Context) bool {
+	l := logging.WithContext(ctx, log)
+	if DB() == nil {
+		l.Error(ErrNoDB)
+		return false
+	}
+
+	var version string
+	if err := DB().QueryRowContext(ctx, "select version()").Scan(&version); err != nil {
+		l.Error(err)
+		return false
+	}
+	l.Debugf("Postgres version: %s", version)
+	return true
+}
+
+// ExecContext runs query on DB, logging it with the request id carried by ctx.
+func ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	l := logging.WithContext(ctx, log)
+	if DB() == nil {
+		l.Error(ErrNoDB)
+		return nil, ErrNoDB
+	}
+
+	start := time.Now()
+	res, err := DB().ExecContext(ctx, query, args...)
+	if err != nil {
+		l.Errorf("Postgres exec %q: %v", query, err)
+		return nil, err
+	}
+	l.Debugf("Postgres exec %q took %s", query, time.Since(start))
+	return res, nil
+}
+
+// QueryContext runs query on DB, logging it with the request id carried by ctx.
+func QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	l := logging.WithContext(ctx, log)
+	if DB() == nil {
+		l.Error(ErrNoDB)
+		return nil, ErrNoDB
+	}
+
+	start := time.Now()
+	rows, err := DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		l.Errorf("Postgres query %q: %v", query, err)
+		return nil, err
+	}
+	l.Debugf("Postgres query %q took %s", query, time.Since(start))
+	return rows, nil
+}
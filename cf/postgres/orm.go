@@ -13,7 +13,11 @@ import (
 var engine *xorm.Engine
 
 func InitORM(env PostgresEnv) *xorm.Engine {
-	uri := settings.PostgresUri(env.Name)
+	uri, err := settings.PostgresUriE(env.Name)
+	if err != nil {
+		log.Errorf("Postgres init ORM error: %v", err)
+		return nil
+	}
 	log.Infof("Postgres Init ORM uri: %s", maskedUrl(uri))
 
 	eng, err := xorm.NewEngine("postgres", uri)
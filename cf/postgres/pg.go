@@ -26,23 +26,36 @@ import (
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 	"net/url"
 )
 
 var settings = config.AppSettings()
-var log = logging.Logger()
+var log = logging.Named("cf/postgres")
+
+// SetLogger makes the package log to l instead of the "cf/postgres" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 var database *sql.DB
 
 type PostgresEnv struct {
-	Name         string `env:"goboot_postgres.name"`
-	MaxOpenConns int    `env:"goboot_postgres.connection.max_open"`
-	MaxIdleConns int    `env:"goboot_postgres.connection.max_idle"`
-	ORMEnable    bool   `env:"goboot_postgres.orm.enable"`
-	ORMShowSQL   bool   `env:"goboot_postgres.orm.show_sql"`
+	Name         string `env:"goboot_postgres.name" desc:"name of the bound postgres service, the postgres label is tried otherwise"`
+	MaxOpenConns int    `env:"goboot_postgres.connection.max_open" desc:"maximum open connections, 0 for unlimited"`
+	MaxIdleConns int    `env:"goboot_postgres.connection.max_idle" desc:"maximum idle connections"`
+	ORMEnable    bool   `env:"goboot_postgres.orm.enable" desc:"connect through the xorm engine"`
+	ORMShowSQL   bool   `env:"goboot_postgres.orm.show_sql" desc:"log the SQL run by the xorm engine"`
+}
+
+// String masks the fields set from secrets, see config.Settings.Redact.
+func (r PostgresEnv) String() string {
+	return settings.Redact(r)
 }
 
 func init() {
+	config.Declare(PostgresEnv{}, "Postgres database connection")
+
 	env := PostgresEnv{}
 
 	err := settings.Parse(&env)
@@ -51,10 +64,15 @@ func init() {
 		return
 	}
 	log.Debugf("Postgres env: %v", env)
+	if config.DeclareOnly() {
+		return
+	}
 
 	if env.ORMEnable {
 		engine = InitORM(env)
-		database = engine.DB().DB
+		if engine != nil {
+			database = engine.DB().DB
+		}
 	} else {
 		database = InitDB(env)
 	}
@@ -67,7 +85,11 @@ func maskedUrl(uri string) string {
 }
 
 func InitDB(env PostgresEnv) *sql.DB {
-	uri := settings.PostgresUri(env.Name)
+	uri, err := settings.PostgresUriE(env.Name)
+	if err != nil {
+		log.Errorf("Postgres init DB error: %v", err)
+		return nil
+	}
 	log.Infof("Postgres init DB uri: %s", maskedUrl(uri))
 
 	db, err := sql.Open("postgres", uri)
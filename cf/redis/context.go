@@ -0,0 +1,64 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gostones/goboot/audit"
+	"github.com/gostones/goboot/logging"
+)
+
+// DoContext runs a redis command like Do, logging it with the request id carried by ctx,
+// see logging.WithContext. The command is not sent if ctx is already done.
+func (r *RedisClient) DoContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	l := logging.WithContext(ctx, log)
+
+	if err := ctx.Err(); err != nil {
+		l.Errorf("redis %s: %v", cmd, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	reply, err := r.Do(cmd, args...)
+	if err != nil {
+		l.Errorf("redis %s: %v", cmd, err)
+		return reply, err
+	}
+	l.Debugf("redis %s took %s", cmd, time.Since(start))
+	return reply, nil
+}
+
+// GetContext returns the value for key like Get, see DoContext.
+func (r *RedisClient) GetContext(ctx context.Context, key string, value interface{}) error {
+	d, err := r.DoContext(ctx, "GET", key)
+	if d == nil || err != nil {
+		return err
+	}
+	return fromJSONString(d.([]uint8), value)
+}
+
+// SetContext applies the value to key like Set, see DoContext.
+func (r *RedisClient) SetContext(ctx context.Context, key string, value interface{}) error {
+	_, err := r.DoContext(ctx, "SET", key, toJSONString(value))
+	return err
+}
+
+// DeleteContext deletes the key like Delete, see DoContext. The deletion is recorded to
+// the audit trail with the actor and the request id of ctx.
+func (r *RedisClient) DeleteContext(ctx context.Context, key string) error {
+	_, err := r.DoContext(ctx, "DEL", key)
+	auditDelete(ctx, key, err)
+	return err
+}
+
+func auditDelete(ctx context.Context, key string, err error) {
+	e := audit.Event{Action: "redis.delete", Target: key, Outcome: audit.Outcome(err)}
+	if err != nil {
+		e.Details = map[string]string{"error": err.Error()}
+	}
+	audit.Record(ctx, e)
+}
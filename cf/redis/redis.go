@@ -1,7 +1,9 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -10,15 +12,37 @@ import (
 	"github.com/garyburd/redigo/redis"
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
 )
 
-var log = logging.Logger()
+var log = logging.Named("cf/redis")
 
+// SetLogger makes the package log to l instead of the "cf/redis" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
+
+// ErrNoPool is returned by a RedisClient created without a bound redis service
+var ErrNoPool = errors.New("redis: no service bound")
+
+// vcap holds the pool settings from the credentials of the first bound service
+// carrying a redis object, usually a user-provided service.
 type vcap struct {
-	MaxIdle     int    `env:"VCAP_SERVICES.user-provided.0.credentials.redis.max_idle"`
-	MaxActive   int    `env:"VCAP_SERVICES.user-provided.0.credentials.redis.max_active"`
-	IdleTimeout string `env:"VCAP_SERVICES.user-provided.0.credentials.redis.idle_timeout"`
-	Wait        bool   `env:"VCAP_SERVICES.user-provided.0.credentials.redis.wait"`
+	MaxIdle     int    `env:"VCAP_SERVICES/*/*/credentials/redis/max_idle" desc:"maximum idle connections in the pool"`
+	MaxActive   int    `env:"VCAP_SERVICES/*/*/credentials/redis/max_active" desc:"maximum connections in the pool, 0 for unlimited"`
+	IdleTimeout string `env:"VCAP_SERVICES/*/*/credentials/redis/idle_timeout" desc:"close connections idle for this duration, e.g. 240s"`
+	Wait        bool   `env:"VCAP_SERVICES/*/*/credentials/redis/wait" desc:"wait for a connection when the pool is at max_active"`
+}
+
+func init() {
+	config.Declare(vcap{}, "Redis connection pool, read from the credentials of a bound (user-provided) service")
+}
+
+// RedisCredentials are the credentials of a bound redis service.
+type RedisCredentials struct {
+	Host     string `cred:"host,required"`
+	Port     int    `cred:"port,required,port"`
+	Password string `cred:"password"`
 }
 
 // GetPoolForService creates a Redigo Pool to connect to Redis service given
@@ -26,15 +50,20 @@ type vcap struct {
 func GetPoolForService(a ...string) *redis.Pool {
 	settings := config.AppSettings()
 
-	s := settings.RedisService(a...)
-	if s == nil {
-		log.Debugf("Redis service not found for: %v", a)
+	s, err := settings.RedisServiceE(a...)
+	if err != nil {
+		log.Debugf("Redis service not found for: %v, %v", a, err)
+		return nil
+	}
+
+	creds := RedisCredentials{}
+	if err := settings.BindService(&creds, s.Name); err != nil {
+		log.Errorf("redis init (credentials) error: %v", err)
 		return nil
 	}
-	v := s.(cfenv.Service)
 
 	vcap := vcap{}
-	err := settings.Parse(&vcap)
+	err = settings.Parse(&vcap)
 	if err != nil {
 		log.Errorf("redis init (vcap) error: %v", err)
 	}
@@ -44,7 +73,7 @@ func GetPoolForService(a ...string) *redis.Pool {
 		log.Errorf("redis init (vcap) error: %v", err)
 	}
 
-	addr := fmt.Sprintf("%s:%g", v.Credentials["host"], v.Credentials["port"])
+	addr := fmt.Sprintf("%s:%d", creds.Host, creds.Port)
 
 	pool := &redis.Pool{
 		MaxIdle:     vcap.MaxIdle,
@@ -57,7 +86,7 @@ func GetPoolForService(a ...string) *redis.Pool {
 				log.Error("Failed to create redis connection: ", err)
 				return nil, err
 			}
-			if pw := v.Credentials["password"]; pw != nil {
+			if pw := creds.Password; pw != "" {
 				if _, err := c.Do("AUTH", pw); err != nil {
 					log.Error("Error authenticating to redis: ", err)
 					c.Close()
@@ -144,39 +173,63 @@ func GetAll(pool *redis.Pool) (interface{}, error) {
 	return value, nil
 }
 
-// Delete deletes the entry for key
+// Delete deletes the entry for key and records it to the audit trail
 func Delete(pool *redis.Pool, key string) error {
 	conn := pool.Get()
 	if conn.Err() != nil {
+		auditDelete(context.Background(), key, conn.Err())
 		return conn.Err()
 	}
 	defer conn.Close()
 
 	_, err := conn.Do("DEL", key)
+	auditDelete(context.Background(), key, err)
 
 	return err
 }
 
 // RedisClient is the redis client
 type RedisClient struct {
-	pool    *redis.Pool
-	setting interface{}
+	pool      *redis.Pool
+	setting   *cfenv.Service
+	available bool
 }
 
 // NewRedisClient returns a redis client
 func NewRedisClient(name ...string) *RedisClient {
 	settings := config.AppSettings()
 
+	s, _ := settings.RedisServiceE(name...)
+
+	pool := GetPoolForService(name...)
+	available := pool != nil
+	if !available {
+		// fail every command instead of panicking
+		pool = &redis.Pool{Dial: func() (redis.Conn, error) {
+			return nil, ErrNoPool
+		}}
+	}
+
 	return &RedisClient{
-		setting: settings.RedisService(name...),
-		pool:    GetPoolForService(name...),
+		setting:   s,
+		pool:      pool,
+		available: available,
 	}
 }
 
-//
+// Available reports whether the client is connected to a bound service.
+// Commands fail with ErrNoPool otherwise.
+func (r *RedisClient) Available() bool {
+	return r.available
+}
+
+// Credentials returns the credentials of the bound service as JSON with the
+// password and other secrets masked, empty if no service is bound
 func (r *RedisClient) Credentials() string {
-	v := r.setting.(cfenv.Service)
-	return toJSONString(v.Credentials)
+	if r.setting == nil {
+		return ""
+	}
+	return toJSONString(logging.Redact(r.setting.Credentials))
 }
 
 // Set applies the value to key
@@ -0,0 +1,65 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gostones/goboot/audit"
+	"github.com/gostones/goboot/config"
+)
+
+// verify checks the hash chain of the audit store of goboot_audit, or of the flags.
+func verify(args []string) error {
+	env := audit.AuditEnv{}
+	if err := config.AppSettings().Parse(&env); err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	flags.StringVar(&env.Store, "store", env.Store, "store `type`, e.g. file or postgres")
+	flags.StringVar(&env.Path, "path", env.Path, "`file` of the file store")
+	flags.StringVar(&env.Table, "table", env.Table, "`table` of the postgres store")
+	asJson := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+	if env.Store == "" {
+		return errors.New("goboot_audit.store not set, see -store")
+	}
+
+	s, err := audit.OpenStore(env)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	report, err := audit.Verify(s)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+		fmt.Printf("%d records, %d problems\n", report.Records, len(report.Problems))
+		if report.Last != nil {
+			fmt.Printf("last: %d %s\n", report.Last.Seq, report.Last.Hash)
+		}
+	}
+	if !report.OK() {
+		return errors.New("audit trail verification failed")
+	}
+	return nil
+}
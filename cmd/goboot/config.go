@@ -0,0 +1,173 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gostones/goboot/config"
+
+	// the packages declaring and parsing goboot settings at init, without connecting
+	// to their services in this command, see config.DeclareOnly
+	_ "github.com/gostones/goboot/cf/blobstore"
+	_ "github.com/gostones/goboot/cf/postgres"
+	_ "github.com/gostones/goboot/cf/redis"
+	_ "github.com/gostones/goboot/elastic"
+	_ "github.com/gostones/goboot/flags"
+	_ "github.com/gostones/goboot/logging"
+	_ "github.com/gostones/goboot/newrelic"
+	"github.com/gostones/goboot/web"
+)
+
+// envReport names the file the child process writes its report to.
+const envReport = "GOBOOT_EXPLAIN_REPORT"
+
+// explain prints the report of config.AppSettings. The settings are resolved by the
+// package inits, before any env file could be applied, so the command runs itself
+// again with the env file added to the environment and prints the report of that run.
+func explain(args []string) error {
+	if file := os.Getenv(envReport); file != "" {
+		return writeReport(file)
+	}
+
+	flags := flag.NewFlagSet("config explain", flag.ExitOnError)
+	asJson := flags.Bool("json", false, "print the report as JSON")
+	out := flags.String("o", "", "write the report to `file` instead of stdout")
+	flags.Parse(args)
+
+	env := os.Environ()
+	if flags.NArg() > 0 {
+		vars, err := readEnvFile(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		env = append(env, vars...)
+	}
+
+	f, err := ioutil.TempFile("", "goboot-explain")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cmd := exec.Command(os.Args[0], "config", "explain")
+	cmd.Env = append(env, envReport+"="+f.Name(), config.EnvDeclareOnly+"=1")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+	var report config.Report
+	if err := json.Unmarshal(b, &report); err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		o, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer o.Close()
+		w = o
+	}
+	if *asJson {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return printReport(w, &report)
+}
+
+// writeReport writes the JSON report to file, in the child process.
+func writeReport(file string) error {
+	// web parses its settings when a server starts
+	config.AppSettings().Parse(&web.AdminEnv{})
+	config.AppSettings().Parse(&web.HttpEnv{})
+
+	b, err := json.Marshal(config.AppSettings().Explain())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0600)
+}
+
+// readEnvFile reads KEY=VALUE lines, skipping blank lines and # comments.
+// An export prefix and quotes around the value are removed.
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		k, v := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		vars = append(vars, k+"="+v)
+	}
+	return vars, scanner.Err()
+}
+
+func printReport(w io.Writer, r *config.Report) error {
+	fmt.Fprintf(w, "profile: %s\nlocal: %v\nsources: %s\n", r.Profile, r.Local, strings.Join(r.Sources, ", "))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range r.Targets {
+		fmt.Fprintf(tw, "\n%s\n", t.Type)
+		fmt.Fprintln(tw, "  FIELD\tKEY\tVALUE\tDEFAULT\tSOURCE\t")
+		for _, f := range t.Fields {
+			source := f.Source
+			if source == "" {
+				source = "-"
+			}
+			if f.Error != "" {
+				source += " (" + f.Error + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%v\t%s\t%s\t\n", f.Field, f.Key, f.Value, f.Default, source)
+		}
+	}
+
+	if len(r.Services) > 0 {
+		fmt.Fprintln(tw, "\nservices")
+		fmt.Fprintln(tw, "  NAME\tSERVICE\tLABEL\tMATCH\t")
+		for _, s := range r.Services {
+			match := s.Match
+			if s.Error != "" {
+				match = s.Error
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t\n", s.Name, s.Service, s.Label, match)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gostones/goboot/config"
+	"gopkg.in/yaml.v2"
+)
+
+// gen writes templates and reference docs for the settings of the declared structs:
+//
+//	<name>.json        sample document per setting, e.g. goboot_postgres.json
+//	goboot.env         .env template with the samples as values
+//	manifest-env.yml   env block for a Cloud Foundry manifest.yml
+//	CONFIG.md          Markdown reference of every setting
+func gen(args []string) error {
+	flags := flag.NewFlagSet("config gen", flag.ExitOnError)
+	dir := flags.String("o", ".", "write the files to `dir`")
+	flags.Parse(args)
+
+	decls := config.Declared()
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Type < decls[j].Type })
+	samples := config.Samples(decls...)
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := map[string][]byte{}
+
+	var env bytes.Buffer
+	block := yaml.MapSlice{}
+	for _, name := range names {
+		b, err := json.MarshalIndent(samples[name], "", "  ")
+		if err != nil {
+			return err
+		}
+		files[name+".json"] = append(b, '\n')
+
+		compact, _ := json.Marshal(samples[name])
+		fmt.Fprintf(&env, "%s='%s'\n", name, strings.Replace(string(compact), "'", `'\''`, -1))
+		block = append(block, yaml.MapItem{Key: name, Value: string(compact)})
+	}
+	files["goboot.env"] = env.Bytes()
+
+	manifest, err := yaml.Marshal(yaml.MapSlice{{Key: "env", Value: block}})
+	if err != nil {
+		return err
+	}
+	files["manifest-env.yml"] = manifest
+	files["CONFIG.md"] = markdown(decls)
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	for name, b := range files {
+		path := filepath.Join(*dir, name)
+		if err := ioutil.WriteFile(path, b, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "wrote", path)
+	}
+	return nil
+}
+
+func markdown(decls []config.Declaration) []byte {
+	var b bytes.Buffer
+	b.WriteString("# Configuration reference\n\n")
+	b.WriteString("Settings are read from the environment, the goboot config files and the bound services.\n")
+	b.WriteString("Generated by `goboot config gen`.\n")
+
+	for _, d := range decls {
+		fmt.Fprintf(&b, "\n## %s\n\n", d.Type)
+		if d.Desc != "" {
+			fmt.Fprintf(&b, "%s.\n\n", strings.TrimSuffix(d.Desc, "."))
+		}
+		b.WriteString("| Key | Type | Default | Required | Description |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, f := range d.Fields {
+			required := ""
+			if f.Required {
+				required = "yes"
+			}
+			desc := f.Desc
+			if f.Rules != "" {
+				desc = strings.TrimSpace(desc + " (" + f.Rules + ")")
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", f.Key, f.Type, code(f.Default), required, cell(desc))
+		}
+	}
+	return b.Bytes()
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+func cell(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command goboot helps inspect goboot apps.
+//
+//	goboot config explain [-json] [-o file] [env-file]
+//	goboot config gen [-o dir]
+//	goboot audit verify [-store type] [-path file] [-table table] [-json]
+//
+// explains the settings the goboot packages resolve, see config.Settings.Explain,
+// for the environment of the command plus the KEY=VALUE lines of env-file.
+// The goboot packages log to stdout while initializing, use -o to keep the report apart.
+// gen writes sample settings, .env and manifest templates and a Markdown reference
+// for the structs the goboot packages declare, see config.Declare.
+// verify checks the hash chain of the audit trail of goboot_audit, see audit.Verify,
+// and prints the last record to keep for later checks.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+const usage = `usage: goboot <command> [arguments]
+
+commands:
+  config explain [-json] [-o file] [env-file]   explain the resolved settings
+  config gen [-o dir]                           generate setting templates and docs
+  audit verify [-store type] [-path file]       verify the audit trail
+`
+
+func main() {
+	// keep stdout for the command output
+	logrus.SetOutput(os.Stderr)
+
+	args := os.Args[1:]
+	var err error
+	switch {
+	case len(args) >= 2 && args[0] == "config" && args[1] == "explain":
+		err = explain(args[2:])
+	case len(args) >= 2 && args[0] == "config" && args[1] == "gen":
+		err = gen(args[2:])
+	case len(args) >= 2 && args[0] == "audit" && args[1] == "verify":
+		err = verify(args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboot: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+)
+
+// On Kubernetes, bindings projected under $SERVICE_BINDING_ROOT following
+// https://servicebinding.io are added to Settings.Env as services, so they are found
+// by the same name and label lookups as VCAP_SERVICES:
+//
+//	$SERVICE_BINDING_ROOT/my-redis/type      -> label "redis"
+//	$SERVICE_BINDING_ROOT/my-redis/provider  -> tag
+//	$SERVICE_BINDING_ROOT/my-redis/host      -> credentials["host"]
+//	$SERVICE_BINDING_ROOT/my-redis/password  -> credentials["password"]
+//
+// The binding directory name is the service name. Types are mapped to the CF labels
+// where they differ, e.g. postgresql to postgres, and a uri credential is composed
+// from host, port, username, password and database if the binding has none.
+
+// EnvServiceBindingRoot is the directory holding the service bindings.
+const EnvServiceBindingRoot = "SERVICE_BINDING_ROOT"
+
+// addBindings adds the services bound under root to app, those of the valid
+// binding directories if others are malformed.
+func addBindings(app *cfenv.App, root string) error {
+	services, err := bindingServices(root)
+	if app.Services == nil {
+		app.Services = cfenv.Services{}
+	}
+	for _, s := range services {
+		app.Services[s.Label] = append(app.Services[s.Label], s)
+	}
+	return err
+}
+
+// bindingServices reads every binding directory under root. The malformed ones
+// are skipped and reported in the error.
+func bindingServices(root string) ([]cfenv.Service, error) {
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []cfenv.Service
+	var errs []string
+	for _, d := range dirs {
+		if strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(root, d.Name())
+		if !isDir(path) {
+			continue
+		}
+		s, err := bindingService(d.Name(), path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("service binding %s: %v", d.Name(), err))
+			continue
+		}
+		services = append(services, s)
+	}
+	if len(errs) > 0 {
+		return services, errors.New(strings.Join(errs, "; "))
+	}
+	return services, nil
+}
+
+func bindingService(name, dir string) (cfenv.Service, error) {
+	s := cfenv.Service{
+		Name:        name,
+		Credentials: make(map[string]interface{}),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return s, err
+	}
+	for _, f := range files {
+		// skip the ..data links of projected volumes
+		path := filepath.Join(dir, f.Name())
+		if strings.HasPrefix(f.Name(), ".") || isDir(path) {
+			continue
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return s, err
+		}
+		s.Credentials[f.Name()] = strings.TrimRight(string(b), "\r\n")
+	}
+
+	typ, _ := s.Credentials["type"].(string)
+	provider, _ := s.Credentials["provider"].(string)
+	if typ == "" {
+		return s, fmt.Errorf("missing type")
+	}
+
+	s.Label = typ
+	if l, ok := schemeLabels[typ]; ok {
+		s.Label = l
+	}
+	s.Tags = []string{typ}
+	if provider != "" {
+		s.Tags = append(s.Tags, provider)
+	}
+
+	if _, ok := s.Credentials["uri"]; !ok {
+		if uri := bindingUri(s.Label, s.Credentials); uri != "" {
+			s.Credentials["uri"] = uri
+		}
+	}
+	return s, nil
+}
+
+// bindingUri composes a uri from the well known binding entries.
+func bindingUri(scheme string, creds map[string]interface{}) string {
+	get := func(k string) string {
+		v, _ := creds[k].(string)
+		return v
+	}
+	host := get("host")
+	if host == "" {
+		return ""
+	}
+	if port := get("port"); port != "" {
+		host += ":" + port
+	}
+
+	u := url.URL{Scheme: scheme, Host: host}
+	if user := get("username"); user != "" {
+		if pw := get("password"); pw != "" {
+			u.User = url.UserPassword(user, pw)
+		} else {
+			u.User = url.User(user)
+		}
+	}
+	if db := get("database"); db != "" {
+		u.Path = "/" + db
+	}
+	return u.String()
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Packages declare the structs they parse so tools can document and template their
+// settings without running them, see the goboot config gen command. Fields may carry
+// a `desc` tag describing the setting:
+//
+//	type PostgresEnv struct {
+//	    Name string `env:"goboot_postgres.name" desc:"name or label of the bound postgres service"`
+//	}
+//
+//	func init() {
+//	    config.Declare(PostgresEnv{}, "Postgres database connection")
+//	}
+
+// EnvDeclareOnly set to any value makes the packages declare and parse their settings
+// at init without connecting to their services, see DeclareOnly.
+const EnvDeclareOnly = "GOBOOT_DECLARE_ONLY"
+
+// the goboot command documents the settings of the packages it imports
+const gobootCommand = "github.com/gostones/goboot/cmd/goboot"
+
+var declareOnly = os.Getenv(EnvDeclareOnly) != "" || mainPath() == gobootCommand
+
+// DeclareOnly reports whether the packages should skip connecting to their services
+// at init, e.g. the agents and clients. It is true if GOBOOT_DECLARE_ONLY is set and
+// in the goboot command.
+func DeclareOnly() bool {
+	return declareOnly
+}
+
+func mainPath() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Path
+	}
+	return ""
+}
+
+// FieldSpec describes a setting bound to a struct field.
+type FieldSpec struct {
+	Field    string `json:"field"`
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Rules    string `json:"rules,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+}
+
+// Declaration describes a declared struct and its settings.
+type Declaration struct {
+	Type   string      `json:"type"`
+	Desc   string      `json:"desc,omitempty"`
+	Fields []FieldSpec `json:"fields"`
+}
+
+var declared struct {
+	list []Declaration
+	sync.Mutex
+}
+
+// Declare registers the struct v, or the struct v points to, with a description.
+// Declaring a type again replaces it.
+func Declare(v interface{}, desc string) {
+	d := Declaration{Type: typeName(v), Desc: desc, Fields: Fields(v)}
+
+	declared.Lock()
+	defer declared.Unlock()
+	for i := range declared.list {
+		if declared.list[i].Type == d.Type {
+			declared.list[i] = d
+			return
+		}
+	}
+	declared.list = append(declared.list, d)
+}
+
+// Declared returns the declared structs in order of declaration.
+func Declared() []Declaration {
+	declared.Lock()
+	defer declared.Unlock()
+	return append([]Declaration(nil), declared.list...)
+}
+
+// Fields describes the `env` tagged fields of the struct v, or the struct v points to,
+// with nested structs flattened.
+func Fields(v interface{}) []FieldSpec {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var specs []FieldSpec
+	fieldSpecs(t, "", "", &specs)
+	return specs
+}
+
+func fieldSpecs(t reflect.Type, prefix, path string, specs *[]FieldSpec) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fpath := joinKey(path, f.Name)
+		key, opts := parseKeyForOption(f.Tag.Get("env"))
+		if key != "" {
+			key = joinKey(prefix, key)
+		}
+
+		if isNested(f.Type) {
+			if key == "" {
+				key = prefix
+			}
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			fieldSpecs(ft, key, fpath, specs)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+
+		var rules []string
+		for j, opt := range opts {
+			if strings.HasPrefix(opt, "regex=") {
+				rules = append(rules, strings.Join(opts[j:], ","))
+				break
+			}
+			if isRule(opt) {
+				rules = append(rules, opt)
+			}
+		}
+		if tag := f.Tag.Get("validate"); tag != "" {
+			rules = append(rules, tag)
+		}
+
+		*specs = append(*specs, FieldSpec{
+			Field:    fpath,
+			Key:      key,
+			Type:     typeLabel(f.Type),
+			Default:  f.Tag.Get("envDefault"),
+			Required: hasOption(opts, "required"),
+			Rules:    strings.Join(rules, ","),
+			Desc:     f.Tag.Get("desc"),
+		})
+	}
+}
+
+// typeLabel names a field type for documentation.
+func typeLabel(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "duration"
+	case t.Kind() == reflect.Ptr:
+		return typeLabel(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return "[]" + typeLabel(t.Elem())
+	case t.Kind() == reflect.Map:
+		return "map[" + typeLabel(t.Key()) + "]" + typeLabel(t.Elem())
+	case isUnmarshaler(t):
+		return t.String()
+	}
+	return t.Kind().String()
+}
+
+// Samples returns a sample document per setting name for the fields of decls, holding
+// the defaults or zero values. Settings provided by the platform (VCAP_*) and keys
+// using wildcards or filters are left out.
+func Samples(decls ...Declaration) map[string]interface{} {
+	m := NewMapSource("", nil).(*mapSource)
+	for _, d := range decls {
+		for _, f := range d.Fields {
+			if p := splitPath(f.Key); strings.HasPrefix(p[0], "VCAP_") || isQuery(p) {
+				continue
+			}
+			m.set(f.Key, sampleValue(f))
+		}
+	}
+	return m.m
+}
+
+func sampleValue(f FieldSpec) interface{} {
+	switch {
+	case f.Type == "bool":
+		b, _ := strconv.ParseBool(f.Default)
+		return b
+	case strings.HasPrefix(f.Type, "int"), strings.HasPrefix(f.Type, "uint"), strings.HasPrefix(f.Type, "float"):
+		n, _ := strconv.ParseFloat(f.Default, 64)
+		return n
+	case strings.HasPrefix(f.Type, "[]"):
+		a := []interface{}{}
+		if f.Default != "" {
+			for _, s := range strings.Split(f.Default, ",") {
+				a = append(a, s)
+			}
+		}
+		return a
+	case strings.HasPrefix(f.Type, "map["):
+		return map[string]interface{}{}
+	}
+	return f.Default
+}
+
+func isQuery(path []string) bool {
+	for _, p := range path {
+		if p == "*" || strings.HasPrefix(p, "[") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type declaredEnv struct {
+	Name    string        `env:"goboot_test.name,required" desc:"service name"`
+	Level   string        `env:"goboot_test.level" envDefault:"INFO" validate:"oneof=DEBUG INFO"`
+	Timeout time.Duration `env:"goboot_test.timeout" envDefault:"5s"`
+	Hosts   []string      `env:"goboot_test.hosts" envDefault:"a,b"`
+	Pool    struct {
+		Size int `env:"size,min=1" envDefault:"4"`
+	} `env:"goboot_test.pool"`
+	Idle    bool `env:"VCAP_SERVICES/*/*/credentials/test/idle"`
+	ignored string
+}
+
+func TestDeclare(t *testing.T) {
+	Declare(&declaredEnv{}, "Test settings")
+
+	var d Declaration
+	for _, decl := range Declared() {
+		if decl.Type == "config.declaredEnv" {
+			d = decl
+		}
+	}
+	assert.Equal(t, "Test settings", d.Desc)
+	assert.Equal(t, []FieldSpec{
+		{Field: "Name", Key: "goboot_test.name", Type: "string", Required: true, Desc: "service name"},
+		{Field: "Level", Key: "goboot_test.level", Type: "string", Default: "INFO", Rules: "oneof=DEBUG INFO"},
+		{Field: "Timeout", Key: "goboot_test.timeout", Type: "duration", Default: "5s"},
+		{Field: "Hosts", Key: "goboot_test.hosts", Type: "[]string", Default: "a,b"},
+		{Field: "Pool.Size", Key: "goboot_test.pool.size", Type: "int", Default: "4", Rules: "min=1"},
+		{Field: "Idle", Key: "VCAP_SERVICES/*/*/credentials/test/idle", Type: "bool"},
+	}, d.Fields)
+
+	assert.Equal(t, map[string]interface{}{
+		"goboot_test": map[string]interface{}{
+			"name":    "",
+			"level":   "INFO",
+			"timeout": "5s",
+			"hosts":   []interface{}{"a", "b"},
+			"pool":    map[string]interface{}{"size": float64(4)},
+		},
+	}, Samples(d))
+}
+
+func TestDeclareOnly(t *testing.T) {
+	// set in the goboot command only, which is not a test
+	assert.Equal(t, os.Getenv(EnvDeclareOnly) != "", DeclareOnly())
+}
@@ -5,23 +5,77 @@
 package config
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func osGetenv(key string) string {
-	p := strings.Split(key, ".")
-	if len(p) == 1 {
-		return settings.GetStringEnv(p[0])
+// Parse loads the `env` tagged fields of the struct v points to from AppSettings.
+//
+// Tags are dotted paths into the settings, e.g. `env:"goboot_postgres.connection.max_open"`,
+// or JSON Pointers and queries, e.g. `env:"VCAP_SERVICES/*/[name=my-redis]/credentials/host"`,
+// see Settings.Query.
+// A tagged struct field makes its tag the prefix of the nested tags, so a whole
+// JSON document binds to one struct:
+//
+//	type DBEnv struct {
+//	    Postgres struct {
+//	        Name       string `env:"name"`
+//	        Connection struct {
+//	            MaxOpen int `env:"max_open"`
+//	        } `env:"connection"`
+//	    } `env:"goboot_postgres"`
+//	}
+//
+// Untagged and embedded structs are walked with the enclosing prefix. Pointers are
+// allocated when a value is present, map[string]T fields are filled from JSON objects
+// (or "k1:v1,k2:v2" strings) and types implementing encoding.TextUnmarshaler,
+// encoding.BinaryUnmarshaler or json.Unmarshaler decode themselves,
+// e.g. net.IP, url.URL and time.Time.
+func Parse(v interface{}) error {
+	return settings.parse(v)
+}
+
+func parse(v interface{}) error {
+	return settings.parse(v)
+}
+
+func (r *Settings) parse(v interface{}) error {
+	var fields []FieldReport
+	d := decoder{
+		tag:    "env",
+		lookup: r.lookupKey,
+		origin: r.originKey,
+		explain: func(f FieldReport) {
+			fields = append(fields, r.explainField(f))
+		},
+	}
+	err := d.parse(v)
+	if err == ErrNotAStructPtr {
+		return err
 	}
-	return settings.GetStringEnv(p[0], p[1:]...)
+	r.explained.addTarget(TargetReport{Type: typeName(v), Fields: fields})
+
+	specs := Fields(v)
+	r.claim(specs)
+	return r.strictCheck(specs, err)
 }
 
-func Parse(v interface{}) error {
-	return parse(v)
+// lookupKey returns the raw value for a dotted key, nil if not set.
+func (r *Settings) lookupKey(key string) interface{} {
+	name, path := splitKey(key)
+	return r.GetEnv(name, path...)
+}
+
+// originKey returns the source for a dotted key.
+func (r *Settings) originKey(key string) string {
+	name, path := splitKey(key)
+	return r.Origin(name, path...)
 }
 
 var (
@@ -32,18 +86,28 @@ var (
 	ErrUnsupportedType = errors.New("Type is not supported")
 	// ErrUnsupportedSliceType if the slice element type is not supported by env
 	ErrUnsupportedSliceType = errors.New("Unsupported slice type")
+	// ErrRequired is reported for required fields without a value
+	ErrRequired = errors.New("Required value is not set")
 	// Friendly names for reflect types
-	sliceOfInts     = reflect.TypeOf([]int(nil))
-	sliceOfInt64s   = reflect.TypeOf([]int64(nil))
-	sliceOfStrings  = reflect.TypeOf([]string(nil))
-	sliceOfBools    = reflect.TypeOf([]bool(nil))
-	sliceOfFloat32s = reflect.TypeOf([]float32(nil))
-	sliceOfFloat64s = reflect.TypeOf([]float64(nil))
+	durationType          = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
 )
 
+// decoder populates structs from values found by lookup, keyed by the paths in tag.
+// origin optionally names the source of a key for error reports.
+type decoder struct {
+	tag     string
+	lookup  func(key string) interface{}
+	origin  func(key string) string
+	explain func(f FieldReport) // called for every field that is not a nested struct, if set
+}
+
 // Parse parses a struct containing `env` tags and loads its values from
-// environment variables.
-func parse(v interface{}) error {
+// the settings sources. All fields are loaded and validated before returning
+// a *ParseError listing every failure.
+func (d decoder) parse(v interface{}) error {
 	ptrRef := reflect.ValueOf(v)
 	if ptrRef.Kind() != reflect.Ptr {
 		return ErrNotAStructPtr
@@ -52,52 +116,119 @@ func parse(v interface{}) error {
 	if ref.Kind() != reflect.Struct {
 		return ErrNotAStructPtr
 	}
-	return doParse(ref)
+	errs := &ParseError{}
+	d.doParse(ref, "", "", errs)
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func doParse(ref reflect.Value) error {
+func (d decoder) doParse(ref reflect.Value, prefix, path string, errs *ParseError) {
 	refType := ref.Type()
 	for i := 0; i < refType.NumField(); i++ {
-		value, err := get(refType.Field(i))
-		if err != nil {
-			return err
+		if refType.Field(i).PkgPath != "" {
+			continue // unexported
 		}
-		if value == "" {
-			continue
+		d.parseField(ref.Field(i), refType.Field(i), prefix, path, errs)
+	}
+}
+
+func (d decoder) parseField(field reflect.Value, refType reflect.StructField, prefix, path string, errs *ParseError) {
+	path = joinKey(path, refType.Name)
+	key, opts := parseKeyForOption(refType.Tag.Get(d.tag))
+	if key != "" {
+		key = joinKey(prefix, key)
+	}
+	f := FieldReport{Field: path, Key: key, Default: refType.Tag.Get("envDefault"), Required: hasOption(opts, "required")}
+	fail := func(err error, value interface{}) {
+		errs.add(path, key, d.source(key), err, value)
+		f.Source, f.Error = d.source(key), errs.Errors[len(errs.Errors)-1].Reason
+	}
+	if d.explain != nil && !isNested(refType.Type) {
+		defer func() {
+			d.explain(f)
+		}()
+	}
+
+	rs, err := parseRules(opts, refType.Tag.Get("validate"))
+	if err == nil {
+		err = checkOptions(opts)
+	}
+	if err != nil {
+		fail(err, nil)
+		return
+	}
+
+	if isNested(refType.Type) {
+		if err := d.parseNested(field, key, prefix, path, opts, errs); err != nil {
+			fail(err, nil)
 		}
-		if err := set(ref.Field(i), refType.Field(i), value); err != nil {
-			return err
+		return
+	}
+
+	value, err := d.get(refType, key, opts)
+	if err != nil {
+		fail(err, nil)
+		return
+	}
+	if isEmpty(value) {
+		if rs.has("nonempty") {
+			fail(ErrEmpty, nil)
 		}
+		f.Value = field.Interface()
+		return
+	}
+	if err := d.set(field, refType, value); err != nil {
+		fail(err, value)
+		return
+	}
+	f.Value = field.Interface()
+	if key != "" && !isEmpty(d.lookup(key)) {
+		f.Source = d.source(key)
+	} else {
+		f.Source = SourceTagDefault
+	}
+	if err := rs.validate(field); err != nil {
+		fail(err, value)
 	}
-	return nil
 }
 
-func get(field reflect.StructField) (string, error) {
-	var (
-		val string
-		err error
-	)
-
-	key, opts := parseKeyForOption(field.Tag.Get("env"))
-
-	defaultValue := field.Tag.Get("envDefault")
-	val = getOr(key, defaultValue)
-
-	if len(opts) > 0 {
-		for _, opt := range opts {
-			// The only option supported is "required".
-			switch opt {
-			case "":
-				break
-			case "required":
-				val, err = getRequired(key)
-			default:
-				err = errors.New("Env tag option " + opt + " not supported.")
+// parseNested walks a struct field. Untagged structs keep the enclosing prefix,
+// tagged ones extend it. Nil struct pointers are only allocated if their path has a value.
+func (d decoder) parseNested(field reflect.Value, key, prefix, path string, opts []string, errs *ParseError) error {
+	if key == "" {
+		key = prefix
+	} else if hasOption(opts, "required") && isEmpty(d.lookup(key)) {
+		return ErrRequired
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			if key == "" || isEmpty(d.lookup(key)) {
+				return nil
 			}
+			field.Set(reflect.New(field.Type().Elem()))
 		}
+		field = field.Elem()
 	}
+	d.doParse(field, key, path, errs)
+	return nil
+}
 
-	return val, err
+func (d decoder) get(field reflect.StructField, key string, opts []string) (interface{}, error) {
+	if hasOption(opts, "required") {
+		return d.getRequired(key)
+	}
+	return d.getOr(key, field.Tag.Get("envDefault")), nil
+}
+
+// source names where the value for key came from, if known.
+func (d decoder) source(key string) string {
+	if d.origin == nil || key == "" {
+		return ""
+	}
+	return d.origin(key)
 }
 
 // split the env tag's key into the expected key and desired option, if any.
@@ -106,27 +237,170 @@ func parseKeyForOption(key string) (string, []string) {
 	return opts[0], opts[1:]
 }
 
-func getRequired(key string) (string, error) {
-	if value := osGetenv(key); value != "" {
+// The options supported are "required" and the validation rules.
+func checkOptions(opts []string) error {
+	for _, opt := range opts {
+		if strings.HasPrefix(opt, "regex=") {
+			break
+		}
+		switch {
+		case opt == "", opt == "required", isRule(opt):
+		default:
+			return errors.New("Env tag option " + opt + " not supported.")
+		}
+	}
+	return nil
+}
+
+func hasOption(opts []string, name string) bool {
+	for _, opt := range opts {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+func joinKey(prefix, key string) string {
+	switch {
+	case prefix == "":
+		return key
+	case strings.Contains(prefix, "/") || strings.Contains(key, "/"):
+		return pointerKey(prefix) + "/" + strings.TrimPrefix(pointerKey(key), "/")
+	}
+	return prefix + "." + key
+}
+
+func (d decoder) getRequired(key string) (interface{}, error) {
+	if value := d.lookup(key); !isEmpty(value) {
 		return value, nil
 	}
-	// We do not use fmt.Errorf to avoid another import.
-	return "", errors.New("Required environment variable " + key + " is not set")
+	return nil, ErrRequired
 }
 
-func getOr(key, defaultValue string) string {
-	value := osGetenv(key)
-	if value != "" {
-		return value
+func (d decoder) getOr(key, defaultValue string) interface{} {
+	if key != "" {
+		if value := d.lookup(key); !isEmpty(value) {
+			return value
+		}
+	}
+	if defaultValue == "" {
+		return nil
 	}
 	return defaultValue
 }
 
-func set(field reflect.Value, refType reflect.StructField, value string) error {
+func isEmpty(value interface{}) bool {
+	return value == nil || value == ""
+}
+
+// isNested reports whether t is a struct (or pointer to one) to be walked field by field
+// rather than decoded from a single value.
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isUnmarshaler(t)
+}
+
+func isUnmarshaler(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return p.Implements(textUnmarshalerType) || p.Implements(binaryUnmarshalerType) || p.Implements(jsonUnmarshalerType)
+}
+
+// toString formats a scalar setting value for parsing.
+// Numbers are never written in exponent form so they parse as integers.
+func toString(value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func (d decoder) set(field reflect.Value, refType reflect.StructField, value interface{}) error {
+	return d.setValue(field, value, refType.Tag.Get("envSeparator"))
+}
+
+func (d decoder) setValue(field reflect.Value, value interface{}, separator string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return d.setValue(field.Elem(), value, separator)
+	}
+
+	if ok, err := unmarshal(field, value); ok {
+		return err
+	}
+
 	switch field.Kind() {
 	case reflect.Slice:
-		separator := refType.Tag.Get("envSeparator")
-		return handleSlice(field, value, separator)
+		return d.handleSlice(field, value, separator)
+	case reflect.Map:
+		return d.handleMap(field, value, separator)
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ErrUnsupportedType
+		}
+		sub := decoder{tag: d.tag, lookup: func(key string) interface{} {
+			return traverse(splitPath(key), m)
+		}}
+		errs := &ParseError{}
+		sub.doParse(field, "", "", errs)
+		if len(errs.Errors) > 0 {
+			return errs
+		}
+		return nil
+	}
+
+	if !isScalar(value) {
+		return ErrUnsupportedType
+	}
+	return setScalar(field, toString(value))
+}
+
+// unmarshal lets types decode themselves, scalars as text and documents as JSON.
+func unmarshal(field reflect.Value, value interface{}) (bool, error) {
+	if !field.CanAddr() {
+		return false, nil
+	}
+	p := field.Addr().Interface()
+
+	if u, ok := p.(encoding.TextUnmarshaler); ok && isScalar(value) {
+		return true, u.UnmarshalText([]byte(toString(value)))
+	}
+	if u, ok := p.(json.Unmarshaler); ok {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return true, err
+		}
+		return true, u.UnmarshalJSON(b)
+	}
+	if u, ok := p.(encoding.BinaryUnmarshaler); ok && isScalar(value) {
+		return true, u.UnmarshalBinary([]byte(toString(value)))
+	}
+	return false, nil
+}
+
+func setScalar(field reflect.Value, value string) error {
+	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
 	case reflect.Bool:
@@ -135,31 +409,25 @@ func set(field reflect.Value, refType reflect.StructField, value string) error {
 			return err
 		}
 		field.SetBool(bvalue)
-	case reflect.Int:
-		intValue, err := strconv.ParseInt(value, 10, 32)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		intValue, err := strconv.ParseInt(value, 10, field.Type().Bits())
 		if err != nil {
 			return err
 		}
 		field.SetInt(intValue)
-	case reflect.Float32:
-		v, err := strconv.ParseFloat(value, 32)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, field.Type().Bits())
 		if err != nil {
 			return err
 		}
 		field.SetFloat(v)
-	case reflect.Float64:
-		v, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			return err
-		}
-		field.Set(reflect.ValueOf(v))
 	case reflect.Int64:
-		if refType.Type.String() == "time.Duration" {
+		if field.Type() == durationType {
 			dValue, err := time.ParseDuration(value)
 			if err != nil {
 				return err
 			}
-			field.Set(reflect.ValueOf(dValue))
+			field.SetInt(int64(dValue))
 		} else {
 			intValue, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
@@ -173,115 +441,73 @@ func set(field reflect.Value, refType reflect.StructField, value string) error {
 	return nil
 }
 
-func handleSlice(field reflect.Value, value, separator string) error {
-	if separator == "" {
-		separator = ","
-	}
-
-	splitData := strings.Split(value, separator)
-
-	switch field.Type() {
-	case sliceOfStrings:
-		field.Set(reflect.ValueOf(splitData))
-	case sliceOfInts:
-		intData, err := parseInts(splitData)
-		if err != nil {
-			return err
-		}
-		field.Set(reflect.ValueOf(intData))
-	case sliceOfInt64s:
-		int64Data, err := parseInt64s(splitData)
-		if err != nil {
-			return err
-		}
-		field.Set(reflect.ValueOf(int64Data))
+// handleSlice fills a slice from a JSON array or a separated string, "," by default.
+func (d decoder) handleSlice(field reflect.Value, value interface{}, separator string) error {
+	var items []interface{}
 
-	case sliceOfFloat32s:
-		data, err := parseFloat32s(splitData)
-		if err != nil {
-			return err
-		}
-		field.Set(reflect.ValueOf(data))
-	case sliceOfFloat64s:
-		data, err := parseFloat64s(splitData)
-		if err != nil {
-			return err
+	switch t := value.(type) {
+	case []interface{}:
+		items = t
+	case map[string]interface{}:
+		return ErrUnsupportedSliceType
+	default:
+		if separator == "" {
+			separator = ","
 		}
-		field.Set(reflect.ValueOf(data))
-	case sliceOfBools:
-		boolData, err := parseBools(splitData)
-		if err != nil {
-			return err
+		for _, s := range strings.Split(toString(value), separator) {
+			items = append(items, s)
 		}
-		field.Set(reflect.ValueOf(boolData))
-	default:
-		return ErrUnsupportedSliceType
 	}
-	return nil
-}
-
-func parseInts(data []string) ([]int, error) {
-	var intSlice []int
 
-	for _, v := range data {
-		intValue, err := strconv.ParseInt(v, 10, 32)
-		if err != nil {
-			return nil, err
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := d.setValue(slice.Index(i), item, ""); err != nil {
+			if err == ErrUnsupportedType {
+				return ErrUnsupportedSliceType
+			}
+			return elementError(fmt.Sprintf("element %d", i), err, item)
 		}
-		intSlice = append(intSlice, int(intValue))
 	}
-	return intSlice, nil
+	field.Set(slice)
+	return nil
 }
 
-func parseInt64s(data []string) ([]int64, error) {
-	var intSlice []int64
-
-	for _, v := range data {
-		intValue, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		intSlice = append(intSlice, int64(intValue))
+// handleMap fills a map with string keys from a JSON object or a "k1:v1,k2:v2" string.
+func (d decoder) handleMap(field reflect.Value, value interface{}, separator string) error {
+	t := field.Type()
+	if t.Key().Kind() != reflect.String {
+		return ErrUnsupportedType
 	}
-	return intSlice, nil
-}
 
-func parseFloat32s(data []string) ([]float32, error) {
-	var float32Slice []float32
+	var items map[string]interface{}
 
-	for _, v := range data {
-		data, err := strconv.ParseFloat(v, 32)
-		if err != nil {
-			return nil, err
+	switch v := value.(type) {
+	case map[string]interface{}:
+		items = v
+	case string:
+		if separator == "" {
+			separator = ","
 		}
-		float32Slice = append(float32Slice, float32(data))
-	}
-	return float32Slice, nil
-}
-
-func parseFloat64s(data []string) ([]float64, error) {
-	var float64Slice []float64
-
-	for _, v := range data {
-		data, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return nil, err
+		items = make(map[string]interface{})
+		for i, pair := range strings.Split(v, separator) {
+			kv := strings.SplitN(pair, ":", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("item %d: expected key:value", i)
+			}
+			items[kv[0]] = kv[1]
 		}
-		float64Slice = append(float64Slice, float64(data))
+	default:
+		return ErrUnsupportedType
 	}
-	return float64Slice, nil
-}
 
-func parseBools(data []string) ([]bool, error) {
-	var boolSlice []bool
-
-	for _, v := range data {
-		bvalue, err := strconv.ParseBool(v)
-		if err != nil {
-			return nil, err
+	m := reflect.MakeMapWithSize(t, len(items))
+	for k, item := range items {
+		elem := reflect.New(t.Elem()).Elem()
+		if err := d.setValue(elem, item, ""); err != nil {
+			return elementError(fmt.Sprintf("item %q", k), err, item)
 		}
-
-		boolSlice = append(boolSlice, bvalue)
+		m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
 	}
-	return boolSlice, nil
+	field.Set(m)
+	return nil
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
 	"os"
 	"testing"
 	"time"
@@ -27,7 +30,7 @@ type Config struct {
 }
 
 func init() {
-	enableCache = false
+	settings.SetProfile(ProfileTest)
 }
 
 func TestParsesEnv(t *testing.T) {
@@ -238,7 +241,7 @@ func ExampleParse() {
 	// Output: {/tmp/fakehome 3000 false}
 }
 
-func ExampleParseRequiredField() {
+func ExampleParse_requiredField() {
 	type config struct {
 		Home         string `env:"HOME"`
 		Port         int    `env:"PORT" envDefault:"3000"`
@@ -249,10 +252,10 @@ func ExampleParseRequiredField() {
 	cfg := config{}
 	err := parse(&cfg)
 	fmt.Println(err)
-	// Output: Required environment variable SECRET_KEY is not set
+	// Output: SecretKey (SECRET_KEY): Required value is not set
 }
 
-func ExampleParseMultipleOptions() {
+func ExampleParse_multipleOptions() {
 	type config struct {
 		Home         string `env:"HOME"`
 		Port         int    `env:"PORT" envDefault:"3000"`
@@ -263,5 +266,103 @@ func ExampleParseMultipleOptions() {
 	cfg := config{}
 	err := parse(&cfg)
 	fmt.Println(err)
-	// Output: Env tag option option1 not supported.
+	// Output: SecretKey (SECRET_KEY): Env tag option option1 not supported.
+}
+
+type color int
+
+func (c *color) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	switch s {
+	case "red":
+		*c = 1
+	case "green":
+		*c = 2
+	default:
+		return fmt.Errorf("unknown color: %s", s)
+	}
+	return nil
+}
+
+func TestParseNested(t *testing.T) {
+	type connection struct {
+		MaxOpen int `env:"max_open"`
+		MaxIdle int `env:"max_idle" envDefault:"2"`
+	}
+	type config struct {
+		Postgres struct {
+			Name       string      `env:"name"`
+			Connection connection  `env:"connection"`
+			ORM        *connection `env:"orm"`
+		} `env:"NESTED"`
+		Missing *connection `env:"NESTED_MISSING"`
+	}
+
+	os.Setenv("NESTED", `{"name": "db", "connection": {"max_open": 10}, "orm": {"max_open": 1}}`)
+	defer os.Setenv("NESTED", "")
+
+	cfg := config{}
+	assert.NoError(t, parse(&cfg))
+	assert.Equal(t, "db", cfg.Postgres.Name)
+	assert.Equal(t, 10, cfg.Postgres.Connection.MaxOpen)
+	assert.Equal(t, 2, cfg.Postgres.Connection.MaxIdle)
+	assert.Equal(t, 1, cfg.Postgres.ORM.MaxOpen)
+	assert.Nil(t, cfg.Missing)
+}
+
+func TestParseMapsPointersAndUnmarshalers(t *testing.T) {
+	type limits struct {
+		Max int `env:"max"`
+	}
+	type config struct {
+		Labels  map[string]string `env:"LABELS"`
+		Limits  map[string]limits `env:"LIMITS"`
+		Weights map[string]int    `env:"WEIGHTS"`
+		Port    *int              `env:"PORT"`
+		IP      net.IP            `env:"IP"`
+		URL     url.URL           `env:"URL"`
+		URLs    []*url.URL        `env:"URLS"`
+		Color   color             `env:"COLOR"`
+		Timeout *time.Duration    `env:"TIMEOUT"`
+	}
+
+	os.Setenv("LABELS", "a:1,b:2")
+	os.Setenv("LIMITS", `{"x": {"max": 3}}`)
+	os.Setenv("WEIGHTS", `{"x": 1, "y": 1000000}`)
+	os.Setenv("PORT", "8080")
+	os.Setenv("IP", "10.0.0.1")
+	os.Setenv("URL", "https://user@example.com/path")
+	os.Setenv("URLS", `["http://a", "http://b"]`)
+	os.Setenv("COLOR", "green")
+	os.Setenv("TIMEOUT", "5s")
+	for _, k := range []string{"LABELS", "LIMITS", "WEIGHTS", "PORT", "IP", "URL", "URLS", "COLOR", "TIMEOUT"} {
+		defer os.Setenv(k, "")
+	}
+
+	cfg := config{}
+	assert.NoError(t, parse(&cfg))
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, cfg.Labels)
+	assert.Equal(t, map[string]limits{"x": {Max: 3}}, cfg.Limits)
+	assert.Equal(t, map[string]int{"x": 1, "y": 1000000}, cfg.Weights)
+	assert.Equal(t, 8080, *cfg.Port)
+	assert.Equal(t, "10.0.0.1", cfg.IP.String())
+	assert.Equal(t, "example.com", cfg.URL.Host)
+	assert.Equal(t, 2, len(cfg.URLs))
+	assert.Equal(t, "b", cfg.URLs[1].Host)
+	assert.Equal(t, color(2), cfg.Color)
+	assert.Equal(t, 5*time.Second, *cfg.Timeout)
+}
+
+func TestParseInvalidUnmarshaler(t *testing.T) {
+	type config struct {
+		Color color `env:"COLOR"`
+	}
+	os.Setenv("COLOR", "blue")
+	defer os.Setenv("COLOR", "")
+
+	cfg := config{}
+	assert.Error(t, parse(&cfg))
 }
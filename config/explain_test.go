@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	s := testServices()
+	s.SetProfile(ProfileTest)
+	s.sources = append(s.sources, NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_test": map[string]interface{}{"name": "my-db", "password": "pw", "port": "x"},
+	}))
+
+	type testEnv struct {
+		Name     string `env:"goboot_test.name"`
+		Password string `env:"goboot_test.password"`
+		Level    string `env:"goboot_test.level" envDefault:"INFO"`
+		Size     int    `env:"goboot_test.size"`
+		Port     int    `env:"goboot_test.port"`
+	}
+	assert.Error(t, s.Parse(&testEnv{}))
+
+	_, err := s.FindService("missing", "postgres")
+	assert.NoError(t, err)
+
+	r := s.Explain()
+	assert.Equal(t, ProfileTest, r.Profile)
+	assert.Equal(t, []string{SourceDefaults, SourceProfileDefaults, SourceProfileOverrides, SourceEnv, SourceFile}, r.Sources)
+	assert.Len(t, r.Targets, 1)
+	assert.Equal(t, "config.testEnv", r.Targets[0].Type)
+	assert.Equal(t, []FieldReport{
+		{Field: "Name", Key: "goboot_test.name", Value: "my-db", Source: SourceFile},
+		{Field: "Password", Key: "goboot_test.password", Value: Redacted, Source: SourceFile},
+		{Field: "Level", Key: "goboot_test.level", Value: "INFO", Default: "INFO", Source: SourceTagDefault},
+		{Field: "Size", Key: "goboot_test.size", Value: 0},
+		{Field: "Port", Key: "goboot_test.port", Source: SourceFile, Error: "invalid syntax"},
+	}, r.Targets[0].Fields)
+	assert.Equal(t, []ServiceReport{
+		{Name: "missing", Error: "service not found"},
+		{Name: "postgres", Service: "my-db", Label: "postgres", Match: MatchLabel},
+	}, r.Services)
+
+	// parsing the type again replaces its report
+	s.Parse(&testEnv{})
+	assert.Len(t, s.Explain().Targets, 1)
+}
+
+func TestExplainMasksValues(t *testing.T) {
+	s := NewSettings(NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_test": map[string]interface{}{
+			"uri":   "postgres://u:hunter22@db:5432/app",
+			"dsn":   "host=db password=hunter22",
+			"hosts": "amqp://guest:guest-pw@mq,redis://cache",
+			"name":  "my-db",
+		},
+	}))
+
+	type testEnv struct {
+		Uri   string   `env:"goboot_test.uri"`
+		Dsn   string   `env:"goboot_test.dsn"`
+		Hosts []string `env:"goboot_test.hosts"`
+		Name  string   `env:"goboot_test.name"`
+	}
+	assert.NoError(t, s.Parse(&testEnv{}))
+
+	fields := s.Explain().Targets[0].Fields
+	assert.Equal(t, "postgres://u:***@db:5432/app", fields[0].Value)
+	assert.Equal(t, "host=db password=***", fields[1].Value)
+	assert.Equal(t, []string{"amqp://guest:***@mq", "redis://cache"}, fields[2].Value)
+	assert.Equal(t, "my-db", fields[3].Value)
+}
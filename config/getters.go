@@ -0,0 +1,125 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The E getters convert the value at name and path like Parse converts fields and
+// return a *FieldError wrapping ErrNotSet if no source sets it, ErrEmpty if it is
+// set to an empty string, or the conversion error. Empty environment variables are
+// not set unless GOBOOT_KEEP_EMPTY is, see EnvKeepEmpty:
+//
+//	timeout, err := settings.GetDurationE("goboot_http", "timeout")
+//	if errors.Is(err, config.ErrNotSet) {
+//	    timeout = 30 * time.Second
+//	}
+
+// ErrNotSet is returned by the E getters when no source sets the value
+var ErrNotSet = errors.New("not set")
+
+// GetStringE returns the string value at name and path. Numbers and booleans are
+// formatted, objects and arrays are an error.
+func (r *Settings) GetStringE(name string, path ...string) (string, error) {
+	var s string
+	err := r.getE(&s, name, path)
+	return s, err
+}
+
+// GetBoolE returns the boolean value at name and path, parsed with strconv.ParseBool.
+func (r *Settings) GetBoolE(name string, path ...string) (bool, error) {
+	var b bool
+	err := r.getE(&b, name, path)
+	return b, err
+}
+
+// GetIntE returns the integer value at name and path.
+func (r *Settings) GetIntE(name string, path ...string) (int, error) {
+	var i int
+	err := r.getE(&i, name, path)
+	return i, err
+}
+
+// GetInt64E returns the 64 bit integer value at name and path.
+func (r *Settings) GetInt64E(name string, path ...string) (int64, error) {
+	var i int64
+	err := r.getE(&i, name, path)
+	return i, err
+}
+
+// GetFloat64E returns the number at name and path.
+func (r *Settings) GetFloat64E(name string, path ...string) (float64, error) {
+	var f float64
+	err := r.getE(&f, name, path)
+	return f, err
+}
+
+// GetDurationE returns the duration at name and path, parsed with time.ParseDuration.
+func (r *Settings) GetDurationE(name string, path ...string) (time.Duration, error) {
+	var d time.Duration
+	err := r.getE(&d, name, path)
+	return d, err
+}
+
+// GetStringSliceE returns the JSON array, or comma separated string, at name and path.
+func (r *Settings) GetStringSliceE(name string, path ...string) ([]string, error) {
+	var a []string
+	err := r.getE(&a, name, path)
+	return a, err
+}
+
+// DecodeEnv decodes the value at name and the dotted path, empty for the whole value,
+// into v with encoding/json, so `json` tags apply.
+func (r *Settings) DecodeEnv(name, path string, v interface{}) error {
+	var p []string
+	if path != "" {
+		p = strings.Split(path, ".")
+	}
+	value, err := r.lookupE(name, p)
+	if err == nil {
+		var b []byte
+		if b, err = json.Marshal(value); err == nil {
+			err = json.Unmarshal(b, v)
+		}
+	}
+	return r.getError(name, p, value, err)
+}
+
+// getE converts the value at name and path into the value ptr points to.
+func (r *Settings) getE(ptr interface{}, name string, path []string) error {
+	value, err := r.lookupE(name, path)
+	if err == nil {
+		err = decoder{}.setValue(reflect.ValueOf(ptr).Elem(), value, "")
+	}
+	return r.getError(name, path, value, err)
+}
+
+func (r *Settings) lookupE(name string, path []string) (interface{}, error) {
+	switch value := r.GetEnv(name, path...); value {
+	case nil:
+		return nil, ErrNotSet
+	case "":
+		return nil, ErrEmpty
+	default:
+		return value, nil
+	}
+}
+
+func (r *Settings) getError(name string, path []string, value interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FieldError{
+		Key:    strings.Join(append([]string{name}, path...), "."),
+		Source: r.Origin(name, path...),
+		Reason: reason(err, value),
+		Err:    err,
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetters(t *testing.T) {
+	s := NewSettings(NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_test": map[string]interface{}{
+			"name":    "test",
+			"empty":   "",
+			"enable":  true,
+			"size":    float64(10),
+			"ratio":   0.5,
+			"timeout": "1m30s",
+			"hosts":   []interface{}{"a", "b"},
+			"tags":    "x,y",
+			"bad":     "abc",
+			"db":      map[string]interface{}{"host": "localhost", "port": float64(5432)},
+		},
+	}))
+
+	str, err := s.GetStringE("goboot_test", "name")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", str)
+
+	b, err := s.GetBoolE("goboot_test", "enable")
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	i, err := s.GetIntE("goboot_test", "size")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, i)
+
+	f, err := s.GetFloat64E("goboot_test", "ratio")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, f)
+
+	d, err := s.GetDurationE("goboot_test", "timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	a, err := s.GetStringSliceE("goboot_test", "hosts")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, a)
+	a, err = s.GetStringSliceE("goboot_test", "tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, a)
+
+	var db struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	assert.NoError(t, s.DecodeEnv("goboot_test", "db", &db))
+	assert.Equal(t, "localhost", db.Host)
+	assert.Equal(t, 5432, db.Port)
+
+	_, err = s.GetStringE("goboot_test", "missing")
+	assert.Equal(t, ErrNotSet, err.(*FieldError).Err)
+	assert.Equal(t, "goboot_test.missing: not set", err.Error())
+
+	_, err = s.GetIntE("goboot_test", "empty")
+	assert.Equal(t, ErrEmpty, err.(*FieldError).Err)
+
+	_, err = s.GetIntE("goboot_test", "bad")
+	assert.Equal(t, "goboot_test.bad from file: invalid syntax", err.Error())
+	assert.Equal(t, 0, s.GetIntEnv("goboot_test", "bad"))
+
+	_, err = s.GetDurationE("goboot_test", "size")
+	assert.Error(t, err)
+	assert.Error(t, s.DecodeEnv("goboot_test", "name", &db))
+}
+
+func TestGettersEmptyEnv(t *testing.T) {
+	os.Setenv("GOBOOT_TEST_EMPTY", "")
+	defer os.Unsetenv("GOBOOT_TEST_EMPTY")
+
+	// empty variables are not set by default
+	s := NewSettings(EnvSource())
+	_, err := s.GetStringE("GOBOOT_TEST_EMPTY")
+	assert.Equal(t, ErrNotSet, err.(*FieldError).Err)
+
+	os.Setenv(EnvKeepEmpty, "true")
+	defer os.Unsetenv(EnvKeepEmpty)
+	s = NewSettings(EnvSource())
+	_, err = s.GetStringE("GOBOOT_TEST_EMPTY")
+	assert.Equal(t, ErrEmpty, err.(*FieldError).Err)
+	assert.Equal(t, "GOBOOT_TEST_EMPTY from env: must not be empty", err.Error())
+
+	_, err = s.GetStringE("GOBOOT_TEST_UNSET")
+	assert.Equal(t, ErrNotSet, err.(*FieldError).Err)
+}
+
+func TestTraverseOutOfRange(t *testing.T) {
+	v := map[string]interface{}{"array": []interface{}{"a", "b"}}
+
+	assert.Equal(t, "b", traverse([]string{"array", "1"}, v))
+	assert.Nil(t, traverse([]string{"array", "2"}, v))
+	assert.Nil(t, traverse([]string{"array", "-1"}, v))
+	assert.Nil(t, traverse([]string{"array", "5", "x"}, v))
+}
@@ -0,0 +1,243 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+)
+
+// Off Cloud Foundry, or with GOBOOT_LOCAL=true, Settings.Env is emulated so apps
+// bound to CF services run unchanged against local services. The app and its
+// services are read from vcap.local.json ($GOBOOT_VCAP_FILE), laid out like the
+// CF environment:
+//
+//	{
+//	  "VCAP_APPLICATION": {"application_name": "my-app"},
+//	  "VCAP_SERVICES": {"postgres": [{"name": "my-db", "credentials": {"uri": "postgres://..."}}]}
+//	}
+//
+// and from variables describing one service each, named after the service:
+//
+//	GOBOOT_SERVICE_MY_REDIS_URI=redis://:secret@localhost:6379
+//	GOBOOT_SERVICE_MY_REDIS_LABEL=redis
+//	GOBOOT_SERVICE_MY_BLOB_CREDENTIALS={"bucket_name": "b", "host": "localhost:9000", ...}
+//
+// A service "my-redis" is bound with the uri and its parts (host, port, username,
+// password, database) as credentials. The label defaults to the uri scheme.
+
+// Environment variables controlling the local emulation.
+const (
+	EnvLocal    = "GOBOOT_LOCAL"
+	EnvVcapFile = "GOBOOT_VCAP_FILE"
+
+	envServicePrefix = "GOBOOT_SERVICE_"
+)
+
+const defaultVcapFile = "vcap.local.json"
+
+// labels for uri schemes that differ from the CF service labels
+var schemeLabels = map[string]string{
+	"postgresql": "postgres",
+	"rediss":     "redis",
+	"amqps":      "amqp",
+}
+
+// currentApp returns the CF app, or the emulated one and true when running locally.
+// Kubernetes service bindings are added to either.
+func currentApp() (*cfenv.App, bool) {
+	app, local := cfApp()
+	if root := os.Getenv(EnvServiceBindingRoot); root != "" {
+		if err := addBindings(app, root); err != nil {
+			fmt.Fprintf(os.Stderr, "goboot: %v\n", err)
+		}
+	}
+	return app, local
+}
+
+func cfApp() (*cfenv.App, bool) {
+	if ok, _ := strconv.ParseBool(os.Getenv(EnvLocal)); !ok {
+		if app, err := cfenv.Current(); err == nil {
+			return app, false
+		}
+	}
+	app, err := localApp(cfenv.CurrentEnv())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goboot: local vcap: %v\n", err)
+		app = &cfenv.App{Services: cfenv.Services{}}
+	}
+	return app, true
+}
+
+// localApp builds the app from the vcap file and GOBOOT_SERVICE_ variables in env.
+func localApp(env map[string]string) (*cfenv.App, error) {
+	doc := map[string]json.RawMessage{}
+
+	file := env[EnvVcapFile]
+	if file == "" {
+		file = defaultVcapFile
+	}
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	case env[EnvVcapFile] != "" || !os.IsNotExist(err):
+		return nil, err
+	}
+
+	vars := make(map[string]string, len(env)+2)
+	for k, v := range env {
+		vars[k] = v
+	}
+	for _, k := range []string{"VCAP_APPLICATION", "VCAP_SERVICES"} {
+		if raw, ok := doc[k]; ok {
+			vars[k] = string(raw)
+		}
+		if strings.TrimSpace(vars[k]) == "" {
+			vars[k] = "{}"
+		}
+	}
+
+	app, err := cfenv.New(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Name == "" {
+		var a struct {
+			Name string `json:"application_name"`
+		}
+		json.Unmarshal([]byte(vars["VCAP_APPLICATION"]), &a)
+		app.Name = a.Name
+	}
+	if app.Name == "" {
+		wd, _ := os.Getwd()
+		app.Name = filepath.Base(wd)
+	}
+	if app.SpaceName == "" {
+		app.SpaceName = "local"
+	}
+
+	services, err := envServices(env)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		app.Services[s.Label] = append(app.Services[s.Label], s)
+	}
+
+	return app, nil
+}
+
+// envServices builds the services described by GOBOOT_SERVICE_<NAME>_URI,
+// _LABEL and _CREDENTIALS variables.
+func envServices(env map[string]string) ([]cfenv.Service, error) {
+	byName := make(map[string]*cfenv.Service)
+
+	service := func(name string) *cfenv.Service {
+		s, ok := byName[name]
+		if !ok {
+			s = &cfenv.Service{
+				Name:        strings.Replace(strings.ToLower(name), "_", "-", -1),
+				Tags:        []string{"local"},
+				Plan:        "local",
+				Credentials: make(map[string]interface{}),
+			}
+			byName[name] = s
+		}
+		return s
+	}
+
+	for k, v := range env {
+		if !strings.HasPrefix(k, envServicePrefix) || v == "" {
+			continue
+		}
+		k = strings.TrimPrefix(k, envServicePrefix)
+
+		switch {
+		case strings.HasSuffix(k, "_URI"):
+			s := service(strings.TrimSuffix(k, "_URI"))
+			// the errors of url.Parse quote the URI, password included
+			u, err := url.Parse(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s%s: invalid URI", envServicePrefix, k)
+			}
+			for ck, cv := range uriCredentials(u) {
+				s.Credentials[ck] = cv
+			}
+			if s.Label == "" {
+				s.Label = u.Scheme
+				if l, ok := schemeLabels[u.Scheme]; ok {
+					s.Label = l
+				}
+			}
+		case strings.HasSuffix(k, "_LABEL"):
+			service(strings.TrimSuffix(k, "_LABEL")).Label = v
+		case strings.HasSuffix(k, "_CREDENTIALS"):
+			s := service(strings.TrimSuffix(k, "_CREDENTIALS"))
+			var creds map[string]interface{}
+			if err := json.Unmarshal([]byte(v), &creds); err != nil {
+				return nil, fmt.Errorf("%s%s: invalid JSON object", envServicePrefix, k)
+			}
+			for ck, cv := range creds {
+				s.Credentials[ck] = cv
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var services []cfenv.Service
+	for _, name := range names {
+		s := byName[name]
+		if s.Label == "" {
+			s.Label = "user-provided"
+		}
+		services = append(services, *s)
+	}
+	return services, nil
+}
+
+// uriCredentials splits a service uri into the credential fields CF brokers commonly use.
+func uriCredentials(u *url.URL) map[string]interface{} {
+	creds := map[string]interface{}{
+		"uri":  u.String(),
+		"host": u.Hostname(),
+	}
+	if p, err := strconv.Atoi(u.Port()); err == nil {
+		creds["port"] = float64(p)
+	}
+	if u.User != nil {
+		creds["username"] = u.User.Username()
+		if pw, ok := u.User.Password(); ok {
+			creds["password"] = pw
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		creds["database"] = db
+		creds["name"] = db
+	}
+	return creds
+}
+
+// IsLocal reports whether Env is emulated rather than read from Cloud Foundry.
+func (r *Settings) IsLocal() bool {
+	return r.local
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// The profile is selected with GOBOOT_PROFILE. It picks the overlay file
+// (e.g. goboot-prod.json), the profile-aware defaults registered by subsystems,
+// and the overrides in the goboot_profiles setting, which holds settings per profile
+// so one document serves every space:
+//
+//	goboot_profiles={
+//	  "dev":  {"goboot_logging": {"level": "DEBUG"}},
+//	  "prod": {"goboot_logging": {"level": "WARN"}, "goboot_newrelic": {"enable": true}}
+//	}
+//
+// Overrides take precedence over the config files but not over the environment.
+// The test profile disables the settings cache.
+
+// Well known profiles.
+const (
+	ProfileDev     = "dev"
+	ProfileTest    = "test"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+// Source names of the profile sources.
+const (
+	SourceProfileDefaults  = "profile-defaults"
+	SourceProfileOverrides = "profile-overrides"
+)
+
+const settingProfiles = "goboot_profiles"
+
+// profile holds the active profile and the profile-aware defaults.
+type profile struct {
+	name     string
+	defaults map[string]*mapSource
+
+	sync.RWMutex
+}
+
+// Profile returns the active profile, empty if none.
+func (r *Settings) Profile() string {
+	r.profile.RLock()
+	defer r.profile.RUnlock()
+	return r.profile.name
+}
+
+// IsProfile reports whether the active profile is one of names.
+func (r *Settings) IsProfile(names ...string) bool {
+	p := r.Profile()
+	for _, name := range names {
+		if p != "" && strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetProfile switches the active profile and clears the cache.
+// The profile overlay file chosen at construction is not changed.
+func (r *Settings) SetProfile(name string) {
+	r.profile.Lock()
+	r.profile.name = strings.ToLower(name)
+	r.profile.Unlock()
+
+	r.Lock()
+	defer r.Unlock()
+	r.cache = make(map[string]interface{})
+}
+
+// SetProfileDefault sets the default for a dotted key that applies when profile is active.
+// Profile defaults take precedence over the defaults set with SetDefault.
+func (r *Settings) SetProfileDefault(profile, key string, value interface{}) {
+	profile = strings.ToLower(profile)
+
+	r.profile.Lock()
+	m, ok := r.profile.defaults[profile]
+	if !ok {
+		m = NewMapSource(SourceProfileDefaults, nil).(*mapSource)
+		r.profile.defaults[profile] = m
+	}
+	r.profile.Unlock()
+
+	m.set(key, value)
+
+	r.Lock()
+	defer r.Unlock()
+	delete(r.cache, "env_"+strings.Split(key, ".")[0])
+}
+
+func (r *Settings) cacheEnabled() bool {
+	return !r.IsProfile(ProfileTest)
+}
+
+func newProfile() profile {
+	return profile{
+		name:     strings.ToLower(os.Getenv(EnvProfile)),
+		defaults: make(map[string]*mapSource),
+	}
+}
+
+// profileDefaults serves the defaults of the active profile.
+type profileDefaults struct {
+	r *Settings
+}
+
+func (s profileDefaults) active() Source {
+	p := &s.r.profile
+	p.RLock()
+	defer p.RUnlock()
+	if m, ok := p.defaults[p.name]; ok {
+		return m
+	}
+	return nil
+}
+
+func (s profileDefaults) Name() string {
+	return SourceProfileDefaults
+}
+
+func (s profileDefaults) Lookup(name string) (interface{}, bool) {
+	if m := s.active(); m != nil {
+		return m.Lookup(name)
+	}
+	return nil, false
+}
+
+func (s profileDefaults) Keys() []string {
+	if m := s.active(); m != nil {
+		return m.Keys()
+	}
+	return nil
+}
+
+// profileOverrides serves the active profile's entry of goboot_profiles.
+type profileOverrides struct {
+	r *Settings
+}
+
+func (s profileOverrides) Name() string {
+	return SourceProfileOverrides
+}
+
+func (s profileOverrides) overrides() map[string]interface{} {
+	p := s.r.Profile()
+	if p == "" {
+		return nil
+	}
+	m, _ := traverse([]string{p}, s.r.merged(settingProfiles)).(map[string]interface{})
+	return m
+}
+
+func (s profileOverrides) Lookup(name string) (interface{}, bool) {
+	if name == settingProfiles {
+		return nil, false
+	}
+	v, ok := s.overrides()[name]
+	return v, ok
+}
+
+func (s profileOverrides) Keys() []string {
+	return sortedKeys(s.overrides())
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	s := NewSettings(NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_test": map[string]interface{}{"level": "DEBUG", "size": 1},
+		"goboot_profiles": map[string]interface{}{
+			"prod": map[string]interface{}{
+				"goboot_test": map[string]interface{}{"level": "WARN"},
+			},
+		},
+	}), EnvSource())
+
+	s.SetDefault("goboot_test.enable", false)
+	s.SetProfileDefault(ProfileProd, "goboot_test.enable", true)
+	s.SetProfileDefault(ProfileProd, "goboot_test.size", 10)
+
+	s.SetProfile(ProfileDev)
+	assert.Equal(t, ProfileDev, s.Profile())
+	assert.True(t, s.IsProfile(ProfileDev, ProfileTest))
+	assert.False(t, s.IsProfile(ProfileProd))
+	assert.Equal(t, "DEBUG", s.GetStringEnv("goboot_test", "level"))
+	assert.False(t, s.GetBoolEnv("goboot_test", "enable"))
+
+	s.SetProfile("PROD")
+	assert.True(t, s.IsProfile(ProfileProd))
+	assert.Equal(t, "WARN", s.GetStringEnv("goboot_test", "level"))
+	assert.Equal(t, SourceProfileOverrides, s.Origin("goboot_test", "level"))
+	assert.True(t, s.GetBoolEnv("goboot_test", "enable"))
+	assert.Equal(t, SourceProfileDefaults, s.Origin("goboot_test", "enable"))
+	assert.Equal(t, 1, s.GetIntEnv("goboot_test", "size"))
+
+	os.Setenv("goboot_test", `{"level": "ERROR"}`)
+	defer os.Unsetenv("goboot_test")
+	s.SetProfile(ProfileProd)
+	assert.Equal(t, "ERROR", s.GetStringEnv("goboot_test", "level"))
+}
+
+func TestTestProfileDisablesCache(t *testing.T) {
+	s := NewSettings(EnvSource())
+	s.SetProfile(ProfileTest)
+
+	os.Setenv("CACHED", "a")
+	assert.Equal(t, "a", s.GetStringEnv("CACHED"))
+	os.Setenv("CACHED", "b")
+	defer os.Unsetenv("CACHED")
+	assert.Equal(t, "b", s.GetStringEnv("CACHED"))
+}
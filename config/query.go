@@ -0,0 +1,143 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Besides dotted keys, names given to GetEnv and keys in `env` tags may be
+// RFC 6901 JSON Pointers, whose first token is the setting name, so keys may
+// contain dots ("~1" escapes "/" and "~0" escapes "~"):
+//
+//	VCAP_SERVICES/p-rabbitmq-3.5/0/credentials/uri
+//	/goboot_feature/rollout.v2
+//
+// Path tokens may also be wildcards or filters, selecting every child or the
+// children whose value at a dotted key equals a string:
+//
+//	VCAP_SERVICES/*/[name=my-redis]/credentials/host
+//	VCAP_SERVICES/*/*/credentials/redis/max_idle
+//
+// An existing key always matches literally. GetEnv returns the first match,
+// taking object keys in sorted order, and Query returns all of them.
+
+// Query returns every value matching the dotted key or pointer query.
+func (r *Settings) Query(query string) []interface{} {
+	name, path := splitKey(query)
+	return match(path, r.getEnv(name))
+}
+
+// splitKey splits a dotted key or pointer into the setting name and path.
+func splitKey(key string) (string, []string) {
+	p := splitPath(key)
+	return p[0], p[1:]
+}
+
+// splitPath splits a dotted key or pointer into its tokens.
+func splitPath(key string) []string {
+	if !strings.Contains(key, "/") {
+		return strings.Split(key, ".")
+	}
+	p := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	for i := range p {
+		p[i] = unescapeToken(p[i])
+	}
+	return p
+}
+
+// splitName splits a pointer passed as name to GetEnv, prepending its path to path.
+func splitName(name string, path []string) (string, []string) {
+	if !strings.Contains(name, "/") {
+		return name, path
+	}
+	name, p := splitKey(name)
+	return name, append(p, path...)
+}
+
+// pointerKey converts a dotted key to a pointer.
+func pointerKey(key string) string {
+	if strings.Contains(key, "/") {
+		return key
+	}
+	p := strings.Split(key, ".")
+	for i := range p {
+		p[i] = escapeToken(p[i])
+	}
+	return strings.Join(p, "/")
+}
+
+func escapeToken(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+func unescapeToken(s string) string {
+	return strings.Replace(strings.Replace(s, "~1", "/", -1), "~0", "~", -1)
+}
+
+// match returns the values at path below t, in order.
+func match(path []string, t interface{}) []interface{} {
+	nodes := []interface{}{t}
+	for _, p := range path {
+		var next []interface{}
+		for _, n := range nodes {
+			next = append(next, step(p, n)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// step returns the children of t selected by the path token p.
+func step(p string, t interface{}) []interface{} {
+	switch n := t.(type) {
+	case map[string]interface{}:
+		if v, ok := n[p]; ok {
+			return []interface{}{v}
+		}
+	case []interface{}:
+		if idx, err := strconv.Atoi(p); err == nil {
+			if idx >= 0 && idx < len(n) {
+				return []interface{}{n[idx]}
+			}
+			return nil
+		}
+	}
+
+	switch {
+	case p == "*":
+		return children(t)
+	case strings.HasPrefix(p, "[") && strings.HasSuffix(p, "]") && strings.Contains(p, "="):
+		i := strings.Index(p, "=")
+		key, value := strings.Split(p[1:i], "."), p[i+1:len(p)-1]
+		var out []interface{}
+		for _, c := range children(t) {
+			if v := traverse(key, c); v != nil && toString(v) == value {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// children returns the elements of an array or the values of an object by sorted key.
+func children(t interface{}) []interface{} {
+	switch n := t.(type) {
+	case map[string]interface{}:
+		out := make([]interface{}, 0, len(n))
+		for _, k := range sortedKeys(n) {
+			out = append(out, n[k])
+		}
+		return out
+	case []interface{}:
+		return n
+	}
+	return nil
+}
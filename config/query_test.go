@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	s := NewSettings(NewMapSource(SourceVcap, map[string]interface{}{
+		"VCAP_SERVICES": map[string]interface{}{
+			"p-rabbitmq-3.5": []interface{}{
+				map[string]interface{}{"name": "mq", "credentials": map[string]interface{}{"uri": "amqp://mq"}},
+			},
+			"user-provided": []interface{}{
+				map[string]interface{}{"name": "config", "credentials": map[string]interface{}{"redis": map[string]interface{}{"max_idle": float64(3)}}},
+				map[string]interface{}{"name": "my-redis", "credentials": map[string]interface{}{"host": "redis-host", "a/b~c": "escaped"}},
+			},
+		},
+	}))
+
+	assert.Equal(t, "amqp://mq", s.GetEnv("VCAP_SERVICES/p-rabbitmq-3.5/0/credentials/uri"))
+	assert.Equal(t, "amqp://mq", s.GetEnv("/VCAP_SERVICES", "p-rabbitmq-3.5", "0", "credentials", "uri"))
+	assert.Equal(t, "redis-host", s.GetStringEnv("VCAP_SERVICES/*/[name=my-redis]/credentials/host"))
+	assert.Equal(t, "escaped", s.GetEnv("VCAP_SERVICES/user-provided/1/credentials/a~1b~0c"))
+	assert.Equal(t, 3, s.GetIntEnv("VCAP_SERVICES/*/*/credentials/redis/max_idle"))
+	assert.Nil(t, s.GetEnv("VCAP_SERVICES/*/[name=missing]/credentials/host"))
+	assert.Nil(t, s.GetEnv("VCAP_SERVICES/user-provided/9/name"))
+
+	assert.Equal(t, []interface{}{"mq", "config", "my-redis"}, s.Query("VCAP_SERVICES/*/*/name"))
+	assert.Equal(t, []interface{}{"my-redis"}, s.Query("VCAP_SERVICES.user-provided.1.name"))
+	assert.Equal(t, SourceVcap, s.Origin("VCAP_SERVICES/*/[name=mq]/credentials/uri"))
+
+	type redisEnv struct {
+		Host    string `env:"VCAP_SERVICES/*/[name=my-redis]/credentials/host"`
+		MaxIdle int    `env:"VCAP_SERVICES/*/*/credentials/redis/max_idle"`
+		Creds   struct {
+			Host string `env:"host"`
+		} `env:"VCAP_SERVICES/user-provided/[name=my-redis]/credentials"`
+	}
+	env := redisEnv{}
+	assert.NoError(t, s.Parse(&env))
+	assert.Equal(t, "redis-host", env.Host)
+	assert.Equal(t, 3, env.MaxIdle)
+	assert.Equal(t, "redis-host", env.Creds.Host)
+}
@@ -0,0 +1,294 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// String values are resolved when read:
+//
+//	${NAME} or ${NAME.path}  replaced by the referenced setting, $${ is a literal ${
+//	file:///run/secrets/pw   replaced by the contents of the file, without the trailing newline
+//	enc:<base64>             decrypted with the AES-GCM key in $GOBOOT_SECRET_KEY
+//	                         or the file named by $GOBOOT_SECRET_KEY_FILE
+//
+// Values read from files or decrypted, and values interpolating them, are marked
+// sensitive and redacted by Settings.String and Settings.Redact.
+
+// Environment variables holding the base64 encoded AES key for enc: values.
+const (
+	EnvSecretKey     = "GOBOOT_SECRET_KEY"
+	EnvSecretKeyFile = "GOBOOT_SECRET_KEY_FILE"
+)
+
+// Redacted replaces sensitive values in output.
+const Redacted = "***"
+
+const (
+	prefixFile = "file://"
+	prefixEnc  = "enc:"
+
+	maxExpandDepth = 8
+)
+
+var (
+	// ErrNoSecretKey is returned when an enc: value is read without a key configured
+	ErrNoSecretKey = errors.New("no secret key, set " + EnvSecretKey + " or " + EnvSecretKeyFile)
+
+	reference = regexp.MustCompile(`\$?\$\{([^}]+)\}`)
+)
+
+// sensitive tracks the keys and values resolved from secrets.
+type sensitive struct {
+	keys   map[string]bool
+	values map[string]bool
+
+	sync.RWMutex
+}
+
+func (r *sensitive) mark(key, value string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.keys == nil {
+		r.keys = make(map[string]bool)
+		r.values = make(map[string]bool)
+	}
+	r.keys[key] = true
+	if value != "" {
+		r.values[value] = true
+	}
+}
+
+// IsSensitive reports whether the value at the dotted key, or any value below it,
+// was resolved from a secret.
+func (r *Settings) IsSensitive(key string) bool {
+	r.sensitive.RLock()
+	defer r.sensitive.RUnlock()
+	for k := range r.sensitive.keys {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretValues returns the values resolved from secrets so far.
+func (r *Settings) SecretValues() []string {
+	r.sensitive.RLock()
+	defer r.sensitive.RUnlock()
+	var values []string
+	for v := range r.sensitive.values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// expand resolves references and secrets in the string values of v, the value at key.
+// Maps and slices are copied so the sources are never modified.
+func (r *Settings) expand(key string, v interface{}, depth int) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = r.expand(key+"."+k, v, depth)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, v := range t {
+			a[i] = r.expand(fmt.Sprintf("%s.%d", key, i), v, depth)
+		}
+		return a
+	case string:
+		s, secret, err := r.expandString(t, depth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goboot: %s: %v\n", key, err)
+			return nil
+		}
+		if secret {
+			r.sensitive.mark(key, s)
+		}
+		return s
+	}
+	return v
+}
+
+// expandString returns the resolved value and whether it came from a secret.
+func (r *Settings) expandString(s string, depth int) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(s, prefixFile):
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", false, err
+		}
+		b, err := ioutil.ReadFile(u.Path)
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	case strings.HasPrefix(s, prefixEnc):
+		key, err := secretKey()
+		if err != nil {
+			return "", false, err
+		}
+		plain, err := DecryptSecret(key, s)
+		return plain, true, err
+	case !strings.Contains(s, "${"):
+		return s, false, nil
+	}
+
+	if depth >= maxExpandDepth {
+		return "", false, fmt.Errorf("references nested too deep: %s", s)
+	}
+
+	secret := false
+	out := reference.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+		ref := m[2 : len(m)-1]
+		name, path := splitKey(ref)
+		v := r.expand(ref, traverse(path, r.merged(name)), depth+1)
+		if r.IsSensitive(ref) {
+			secret = true
+		}
+		return toString(v)
+	})
+	return out, secret, nil
+}
+
+func secretKey() ([]byte, error) {
+	k := os.Getenv(EnvSecretKey)
+	if f := os.Getenv(EnvSecretKeyFile); k == "" && f != "" {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		k = strings.TrimSpace(string(b))
+	}
+	if k == "" {
+		return nil, ErrNoSecretKey
+	}
+	return base64.StdEncoding.DecodeString(k)
+}
+
+// EncryptSecret encrypts plain with the AES key (16, 24 or 32 bytes) and returns
+// an enc: value for use in settings.
+func EncryptSecret(key []byte, plain string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return prefixEnc + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts an enc: value produced by EncryptSecret.
+func DecryptSecret(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefixEnc))
+	if err != nil {
+		return "", err
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", errors.New("encrypted value too short")
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// redact replaces the sensitive values below key.
+func (r *Settings) redact(key string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = r.redact(key+"."+k, v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, v := range t {
+			a[i] = r.redact(fmt.Sprintf("%s.%d", key, i), v)
+		}
+		return a
+	}
+	if r.IsSensitive(key) {
+		return Redacted
+	}
+	return v
+}
+
+// Redact formats the struct v points to like %v, replacing fields whose `env` key
+// resolved from a secret. Env structs use it in their String method so they can be logged.
+func (r *Settings) Redact(v interface{}) string {
+	ref := reflect.Indirect(reflect.ValueOf(v))
+	if ref.Kind() != reflect.Struct {
+		return fmt.Sprintf("%v", v)
+	}
+	return r.redactStruct(ref, "")
+}
+
+func (r *Settings) redactStruct(ref reflect.Value, prefix string) string {
+	refType := ref.Type()
+	var fields []string
+	for i := 0; i < refType.NumField(); i++ {
+		f := refType.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		key, _ := parseKeyForOption(f.Tag.Get("env"))
+		if key != "" {
+			key = joinKey(prefix, key)
+		} else {
+			key = prefix
+		}
+
+		fv := ref.Field(i)
+		switch {
+		case key != "" && f.Tag.Get("env") != "" && r.IsSensitive(key) && !isNested(f.Type):
+			fields = append(fields, Redacted)
+		case isNested(f.Type) && reflect.Indirect(fv).IsValid():
+			fields = append(fields, r.redactStruct(reflect.Indirect(fv), key))
+		default:
+			fields = append(fields, fmt.Sprintf("%v", fv.Interface()))
+		}
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
@@ -0,0 +1,213 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+)
+
+var (
+	// ErrServiceNotFound is returned when no bound service matches the names or labels
+	ErrServiceNotFound = errors.New("service not found")
+	// ErrServiceAmbiguous is returned when a label matches more than one bound service
+	ErrServiceAmbiguous = errors.New("service label is ambiguous")
+)
+
+// ServiceError describes a failed service lookup or credentials binding.
+type ServiceError struct {
+	Names   []string // names and labels tried
+	Matches []string // services sharing an ambiguous label
+	Err     error
+}
+
+func (e *ServiceError) Error() string {
+	if len(e.Matches) > 0 {
+		return fmt.Sprintf("%v: %s matches %s", e.Err, strings.Join(e.Names, ", "), strings.Join(e.Matches, ", "))
+	}
+	return fmt.Sprintf("%v: %s", e.Err, strings.Join(e.Names, ", "))
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// findService looks up by name and then by label. A label shared by several
+// services is an error rather than an arbitrary pick, unless first is set:
+// the legacy lookups return the first service with the label.
+func (r *Settings) findService(name string, first bool) (*cfenv.Service, []string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := "service_" + name
+
+	if t, ok := r.cache[key]; ok {
+		return t.(*cfenv.Service), nil, nil
+	}
+
+	if r.Env == nil {
+		return nil, nil, ErrServiceNotFound
+	}
+
+	s, err := r.Env.Services.WithName(name)
+	if err != nil {
+		ss, err := r.Env.Services.WithLabel(name)
+		if err != nil || len(ss) == 0 {
+			return nil, nil, ErrServiceNotFound
+		}
+		if len(ss) > 1 && first {
+			return &ss[0], nil, nil
+		}
+		if len(ss) > 1 {
+			var names []string
+			for _, s := range ss {
+				names = append(names, s.Name)
+			}
+			return nil, names, ErrServiceAmbiguous
+		}
+		s = &ss[0]
+	}
+	r.cache[key] = s
+
+	return s, nil, nil
+}
+
+// FindService looks up by name and then by label and returns the first service
+// from VCAP_SERVICES matching one of the names. A label shared by several services
+// is an ErrServiceAmbiguous error.
+func (r *Settings) FindService(names ...string) (*cfenv.Service, error) {
+	return r.lookupService(false, names)
+}
+
+func (r *Settings) lookupService(first bool, names []string) (*cfenv.Service, error) {
+	var tried []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		tried = append(tried, name)
+		s, matches, err := r.findService(name, first)
+		r.explainService(name, s, err)
+		if err == nil {
+			return s, nil
+		}
+		if err != ErrServiceNotFound {
+			return nil, &ServiceError{Names: []string{name}, Matches: matches, Err: err}
+		}
+	}
+	return nil, &ServiceError{Names: tried, Err: ErrServiceNotFound}
+}
+
+// GetService looks up by name and then by label and returns the service
+// from VCAP_SERVICES environment variable, nil if not found.
+// A label shared by several services returns the first of them.
+func (r *Settings) GetService(names ...string) interface{} {
+	s, err := r.lookupService(true, names)
+	if err != nil {
+		return nil
+	}
+	return *s
+}
+
+// BindService decodes the credentials of the first service matching names into
+// the struct creds points to. Fields are bound with `cred` tags holding dotted
+// paths into the credentials, supporting the same options, types and validation
+// rules as `env` tags in Parse:
+//
+//	type redisCredentials struct {
+//	    Host     string `cred:"host,required"`
+//	    Port     int    `cred:"port,required,port"`
+//	    Password string `cred:"password"`
+//	}
+func (r *Settings) BindService(creds interface{}, names ...string) error {
+	s, err := r.FindService(names...)
+	if err != nil {
+		return err
+	}
+	d := decoder{
+		tag: "cred",
+		lookup: func(key string) interface{} {
+			return traverse(splitPath(key), map[string]interface{}(s.Credentials))
+		},
+		origin: func(string) string {
+			return "service " + s.Name
+		},
+	}
+	if err := d.parse(creds); err != nil {
+		return &ServiceError{Names: []string{s.Name}, Err: err}
+	}
+	return nil
+}
+
+// RedisServiceE returns the redis service bound with one of the names or a well known label.
+// Like GetService, a label shared by several services returns the first of them.
+func (r *Settings) RedisServiceE(a ...string) (*cfenv.Service, error) {
+	a = append(a, "aws-redis", "redis", "redis-1", "redis-11", "redis-13")
+	return r.lookupService(true, a)
+}
+
+// RedisService returns the cfenv.Service of RedisServiceE, nil if not found.
+//
+// Deprecated: use RedisServiceE, which reports why the lookup failed.
+func (r *Settings) RedisService(a ...string) interface{} {
+	s, err := r.RedisServiceE(a...)
+	if err != nil {
+		return nil
+	}
+	return *s
+}
+
+// PostgresUriE returns the uri of the postgres service bound with one of the names
+// or the postgres label.
+func (r *Settings) PostgresUriE(a ...string) (string, error) {
+	a = append(a, "postgres")
+	return r.ServiceUriE(a...)
+}
+
+// PostgresUri returns the uri of PostgresUriE, "" if not found.
+//
+// Deprecated: use PostgresUriE, which reports why the lookup failed.
+func (r *Settings) PostgresUri(a ...string) string {
+	uri, _ := r.PostgresUriE(a...)
+	return uri
+}
+
+// RabbitmqUriE returns the uri of the rabbitmq service bound with one of the names
+// or a well known label.
+func (r *Settings) RabbitmqUriE(a ...string) (string, error) {
+	a = append(a, "rabbitmq-36", "p-rabbitmq-35")
+	return r.ServiceUriE(a...)
+}
+
+// RabbitmqUri returns the uri of RabbitmqUriE, "" if not found.
+//
+// Deprecated: use RabbitmqUriE, which reports why the lookup failed.
+func (r *Settings) RabbitmqUri(a ...string) string {
+	uri, _ := r.RabbitmqUriE(a...)
+	return uri
+}
+
+// ServiceUriE looks up by name and then by label and returns service uri
+// from the VCAP_SERVICES environment variable
+func (r *Settings) ServiceUriE(names ...string) (string, error) {
+	var creds struct {
+		Uri string `cred:"uri,required"`
+	}
+	if err := r.BindService(&creds, names...); err != nil {
+		return "", err
+	}
+	return creds.Uri, nil
+}
+
+// ServiceUri returns the uri of ServiceUriE, "" if not found.
+//
+// Deprecated: use ServiceUriE, which reports why the lookup failed.
+func (r *Settings) ServiceUri(names ...string) string {
+	uri, _ := r.ServiceUriE(names...)
+	return uri
+}
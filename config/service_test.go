@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServices() *Settings {
+	s := NewSettings(EnvSource())
+	s.Env = &cfenv.App{Services: cfenv.Services{
+		"postgres": {
+			{Name: "my-db", Label: "postgres", Credentials: map[string]interface{}{"uri": "postgres://u:p@host/db"}},
+		},
+		"redis": {
+			{Name: "cache-a", Label: "redis", Credentials: map[string]interface{}{"host": "a", "port": float64(6379)}},
+			{Name: "cache-b", Label: "redis", Credentials: map[string]interface{}{"host": "b"}},
+		},
+	}}
+	return s
+}
+
+func TestBindService(t *testing.T) {
+	s := testServices()
+
+	type redisCreds struct {
+		Host     string `cred:"host,required"`
+		Port     int    `cred:"port,required"`
+		Password string `cred:"password"`
+	}
+
+	creds := redisCreds{}
+	assert.NoError(t, s.BindService(&creds, "", "cache-a"))
+	assert.Equal(t, redisCreds{Host: "a", Port: 6379}, creds)
+
+	err := s.BindService(&redisCreds{}, "cache-b")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Port (port from service cache-b): Required value is not set")
+
+	err = s.BindService(&redisCreds{}, "redis")
+	assert.Error(t, err)
+	assert.Equal(t, ErrServiceAmbiguous, err.(*ServiceError).Err)
+	assert.Equal(t, "service label is ambiguous: redis matches cache-a, cache-b", err.Error())
+
+	err = s.BindService(&redisCreds{}, "missing", "other")
+	assert.Error(t, err)
+	assert.Equal(t, "service not found: missing, other", err.Error())
+}
+
+func TestServiceUri(t *testing.T) {
+	s := testServices()
+
+	uri, err := s.PostgresUriE()
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://u:p@host/db", uri)
+
+	_, err = s.RabbitmqUriE()
+	assert.Error(t, err)
+
+	assert.Nil(t, s.GetService("missing"))
+	assert.Equal(t, "my-db", s.GetService("postgres").(cfenv.Service).Name)
+
+	// the deprecated lookups return the zero value on errors
+	assert.Equal(t, "postgres://u:p@host/db", s.PostgresUri())
+	assert.Equal(t, "postgres://u:p@host/db", s.ServiceUri("my-db"))
+	assert.Equal(t, "", s.RabbitmqUri())
+	assert.Equal(t, "cache-a", s.RedisService().(cfenv.Service).Name)
+}
+
+func TestSharedLabel(t *testing.T) {
+	s := testServices()
+
+	// the legacy lookups keep returning the first match
+	assert.Equal(t, "cache-a", s.GetService("redis").(cfenv.Service).Name)
+	redis, err := s.RedisServiceE()
+	assert.NoError(t, err)
+	assert.Equal(t, "cache-a", redis.Name)
+
+	_, err = s.FindService("redis")
+	assert.Equal(t, ErrServiceAmbiguous, err.(*ServiceError).Err)
+}
@@ -31,135 +31,146 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry-community/go-cfenv"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Settings resolves values from an ordered list of sources. Later sources take
+// precedence: built-in defaults, the config file ($GOBOOT_CONFIG or
+// goboot.json/goboot.yaml/goboot.yml), the profile overlay file
+// (e.g. goboot-dev.json for GOBOOT_PROFILE=dev), the profile overrides, the bound
+// Cloud Foundry services and finally the environment variables.
+// Profile-aware defaults apply on top of the built-in defaults, see Profile.
+// Off Cloud Foundry Env is emulated from local service definitions, see IsLocal.
+// JSON objects are merged across sources so each path resolves independently.
 type Settings struct {
-	Env *cfenv.App
+	Env    *cfenv.App
+	local  bool
+	strict string
+
+	sources  []Source
+	defaults *mapSource
+	profile  profile
 
 	cache map[string]interface{} //cached env and uris
 
+	watchers  watchers
+	sensitive sensitive
+	explained explained
+
 	sync.Mutex
 }
 
-func (r Settings) String() string {
-	return fmt.Sprintf("%s", r.cache)
+func (r *Settings) String() string {
+	r.Lock()
+	defer r.Unlock()
+
+	m := make(map[string]interface{}, len(r.cache))
+	for k, v := range r.cache {
+		if strings.HasPrefix(k, "env_") {
+			v = r.redact(strings.TrimPrefix(k, "env_"), v)
+		}
+		m[k] = v
+	}
+	return fmt.Sprintf("%s", m)
 }
 
-func (r Settings) getEnv(name string) interface{} {
+func (r *Settings) getEnv(name string) interface{} {
 	r.Lock()
 	defer r.Unlock()
-	if !enableCache {
-		return os.Getenv(name)
+	if !r.cacheEnabled() {
+		return r.resolve(name)
 	}
 
 	key := "env_" + name
 
-	var t interface{}
-	var ok bool
-	if t, ok = r.cache[key]; ok {
+	if t, ok := r.cache[key]; ok {
 		return t
 	}
 
-	v := os.Getenv(name)
-	if err := json.Unmarshal([]byte(v), &t); err != nil {
-		r.cache[key] = v
-		return v
-	}
+	t := r.resolve(name)
 	r.cache[key] = t
 	return t
 }
 
-func (r Settings) getService(name string) interface{} {
-	r.Lock()
-	defer r.Unlock()
-
-	key := "service_" + name
+// resolve returns the merged value for name with references and secrets resolved.
+func (r *Settings) resolve(name string) interface{} {
+	return r.expand(name, r.merged(name), 0)
+}
 
+// merged merges the values for name across all sources, lowest priority first.
+func (r *Settings) merged(name string) interface{} {
 	var t interface{}
-	var ok bool
-	if t, ok = r.cache[key]; ok {
-		return t
-	}
-
-	s, err := r.Env.Services.WithName(name)
-	if err != nil {
-		ss, err := r.Env.Services.WithLabel(name)
-		if err != nil {
-			t = nil
-		} else {
-
-			t = ss[0]
+	for _, s := range r.sources {
+		if v, ok := s.Lookup(name); ok {
+			t = merge(t, v)
 		}
-	} else {
-
-		t = *s
 	}
-	r.cache[key] = t
-
 	return t
 }
 
-// GetService looks up by name and then by label and returns the service
-// from VCAP_SERVICES environment variable
-func (r Settings) GetService(names ...string) interface{} {
-	for _, name := range names {
-		if name == "" {
-			continue
-		}
-		s := r.getService(name)
-		if s != nil {
-			return s
-		}
-	}
-
-	return nil
+// Sources returns the sources in the order they are applied.
+func (r *Settings) Sources() []Source {
+	return append([]Source(nil), r.sources...)
 }
 
-func (r *Settings) RedisService(a ...string) interface{} {
-	a = append(a, "aws-redis", "redis", "redis-1", "redis-11", "redis-13")
-	s := settings.GetService(a...)
-	return s
-}
+// SetDefault sets the built-in default for a dotted key, e.g. "goboot_logging.level".
+// Defaults have the lowest precedence of all sources.
+func (r *Settings) SetDefault(key string, value interface{}) {
+	r.defaults.set(key, value)
 
-func (r Settings) PostgresUri(a ...string) string {
-	a = append(a, "postgres")
-	return r.ServiceUri(a...)
+	r.Lock()
+	defer r.Unlock()
+	delete(r.cache, "env_"+strings.Split(key, ".")[0])
 }
 
-func (r Settings) RabbitmqUri(a ...string) string {
-	a = append(a, "rabbitmq-36", "p-rabbitmq-35")
-	return r.ServiceUri(a...)
+// Origin returns the name of the source that supplies the value for name and path,
+// or an empty string if the value is not set.
+func (r *Settings) Origin(name string, path ...string) string {
+	name, path = splitName(name, path)
+	for i := len(r.sources) - 1; i >= 0; i-- {
+		v, ok := r.sources[i].Lookup(name)
+		if !ok {
+			continue
+		}
+		if traverse(path, v) != nil {
+			return r.sources[i].Name()
+		}
+	}
+	return ""
 }
 
-// ServiceUri looks up by name and then by label and returns service uri
-// from the VCAP_SERVICES environment variable
-func (r Settings) ServiceUri(names ...string) string {
-	return r.GetService(names...).(cfenv.Service).Credentials["uri"].(string)
+// Origins reports the winning source for every dotted key known to the sources.
+func (r *Settings) Origins() map[string]string {
+	origins := make(map[string]string)
+	for _, s := range r.sources {
+		for _, name := range s.Keys() {
+			v, _ := s.Lookup(name)
+			leaves := make(map[string]interface{})
+			flatten(name, v, leaves)
+			for k := range leaves {
+				origins[k] = s.Name()
+			}
+		}
+	}
+	return origins
 }
 
 // GetEnv returns env value for the given name.
 // If the value is JSON and path is provided, return the part specified.
-func (r Settings) GetEnv(name string, path ...string) interface{} {
-	v := r.getEnv(name)
-	if v == nil || len(path) == 0 {
-		return v
-	}
-	m, ok := v.(map[string]interface{})
-	if !ok || m == nil {
-		return nil
-	}
-	return traverse(path, m)
+// The name may also be a JSON Pointer or query, see Query.
+func (r *Settings) GetEnv(name string, path ...string) interface{} {
+	name, path = splitName(name, path)
+	return traverse(path, r.getEnv(name))
 }
 
 // GetEnv returns env string value for the given name.
 // If the value is JSON and path is provided, return the part specified.
-func (r Settings) GetStringEnv(name string, path ...string) string {
+func (r *Settings) GetStringEnv(name string, path ...string) string {
 	t := r.GetEnv(name, path...)
 
 	switch t.(type) {
@@ -174,81 +185,108 @@ func (r Settings) GetStringEnv(name string, path ...string) string {
 	return fmt.Sprintf("%v", t)
 }
 
-// GetEnv returns env boolean value for the given name.
+// GetEnv returns env boolean value for the given name, false if not set or malformed.
 // If the value is JSON and path is provided, return the part specified.
-func (r Settings) GetBoolEnv(name string, path ...string) bool {
-	t := r.GetEnv(name, path...)
+// Use GetBoolE to tell these cases apart.
+func (r *Settings) GetBoolEnv(name string, path ...string) bool {
+	b, _ := r.GetBoolE(name, path...)
+	return b
+}
 
-	b, err := strconv.ParseBool(fmt.Sprintf("%v", t))
-	if err == nil {
-		return b
+// GetEnv returns env int value for the given name, 0 if not set or malformed.
+// If the value is JSON and path is provided, return the part specified.
+// Use GetIntE to tell these cases apart.
+func (r *Settings) GetIntEnv(name string, path ...string) int {
+	i, _ := r.GetIntE(name, path...)
+	return i
+}
+
+func (r *Settings) Parse(v interface{}) error {
+	return r.parse(v)
+}
+
+// traverse returns the first value at path below t, see Query for the path syntax.
+func traverse(path []string, t interface{}) interface{} {
+	if m := match(path, t); len(m) > 0 {
+		return m[0]
 	}
-	return false
+	return nil
 }
 
-// GetEnv returns env int value for the given name.
-// If the value is JSON and path is provided, return the part specified.
-func (r Settings) GetIntEnv(name string, path ...string) int {
-	t := r.GetEnv(name, path...)
+// NewSettings creates Settings reading from the given sources in order of
+// increasing precedence. Without arguments the default source chain is used.
+// The defaults and the profile sources are added to the chain, the overrides of
+// goboot_profiles below the first vcap or env source.
+func NewSettings(sources ...Source) *Settings {
+	env, local := currentApp()
+
+	r := &Settings{
+		Env:      env,
+		local:    local,
+		defaults: NewMapSource(SourceDefaults, nil).(*mapSource),
+		profile:  newProfile(),
+		cache:    make(map[string]interface{}),
+		strict:   os.Getenv(EnvStrict),
+	}
 
-	i, err := strconv.Atoi(fmt.Sprintf("%v", t))
-	if err == nil {
-		return i
+	if len(sources) == 0 {
+		sources = defaultSources(r)
 	}
+	r.sources = append([]Source{r.defaults, profileDefaults{r}}, withOverrides(r, sources)...)
 
-	return 0
+	return r
 }
 
-func (r Settings) Parse(v interface{}) error {
-	return parse(v)
+// withOverrides inserts the profile overrides below the first vcap or env source,
+// on top of the others.
+func withOverrides(r *Settings, sources []Source) []Source {
+	i := 0
+	for i < len(sources) && sources[i].Name() != SourceVcap && sources[i].Name() != SourceEnv {
+		i++
+	}
+	list := append([]Source{}, sources[:i]...)
+	list = append(list, profileOverrides{r})
+	return append(list, sources[i:]...)
 }
 
-func traverse(path []string, t interface{}) interface{} {
-	var next = func() interface{} {
-		switch t.(type) {
-		case []interface{}:
-			idx, err := strconv.Atoi(path[0])
-			if err == nil {
-				return t.([]interface{})[idx]
+// defaultSources returns the file, profile, vcap and env sources.
+// A config file that fails to load is reported and skipped.
+func defaultSources(r *Settings) []Source {
+	var sources []Source
+
+	file := configFile()
+	for _, f := range []struct{ name, path string }{
+		{SourceFile, file},
+		{SourceProfile, profileFile(file, os.Getenv(EnvProfile))},
+	} {
+		if f.path == "" {
+			continue
+		}
+		s, err := NewFileSource(f.name, f.path)
+		if err != nil {
+			if !os.IsNotExist(err) || f.path == os.Getenv(EnvConfigFile) {
+				fmt.Fprintf(os.Stderr, "goboot: %v\n", err)
 			}
-		case map[string]interface{}:
-			return t.(map[string]interface{})[path[0]]
+			continue
 		}
-		return nil
+		sources = append(sources, s)
 	}
 
-	switch len(path) {
-	case 0:
-		return t
-	case 1:
-		return next()
-	default:
-		v := next()
-		switch v.(type) {
-		case []interface{}:
-			return traverse(path[1:], v)
-		case map[string]interface{}:
-			return traverse(path[1:], v)
-		}
-	}
+	sources = append(sources, vcapSource{env: func() *cfenv.App { return r.Env }}, EnvSource())
 
-	return nil
+	return sources
 }
 
-func NewSettings() *Settings {
-	env, _ := cfenv.Current()
+var (
+	settings = NewSettings()
+)
 
-	return &Settings{
-		Env:   env,
-		cache: make(map[string]interface{}),
+func init() {
+	if d, err := time.ParseDuration(os.Getenv(EnvWatch)); err == nil && d >= 0 {
+		settings.Watch(d)
 	}
 }
 
-var (
-	settings    = NewSettings()
-	enableCache = true // set to false for testing
-)
-
 func AppSettings() *Settings {
 	return settings
 }
@@ -0,0 +1,414 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-community/go-cfenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Source provides top level values by name, e.g. "goboot_postgres" or "PORT".
+// Values are either scalars or decoded JSON documents.
+type Source interface {
+	// Name identifies the source when reporting where a value came from.
+	Name() string
+	// Lookup returns the value for the top level name if the source has one.
+	Lookup(name string) (interface{}, bool)
+	// Keys returns the top level names the source has values for.
+	Keys() []string
+}
+
+// Source names of the built-in sources, lowest priority first.
+const (
+	SourceDefaults = "defaults"
+	SourceFile     = "file"
+	SourceProfile  = "profile"
+	SourceVcap     = "vcap"
+	SourceEnv      = "env"
+)
+
+// Environment variables selecting the config files.
+const (
+	EnvConfigFile = "GOBOOT_CONFIG"
+	EnvProfile    = "GOBOOT_PROFILE"
+)
+
+// EnvKeepEmpty makes variables set to an empty string, e.g. X=, override the other
+// sources if set to true. By default they are treated as not set.
+const EnvKeepEmpty = "GOBOOT_KEEP_EMPTY"
+
+// default config file names looked up in the working directory
+var configFiles = []string{"goboot.json", "goboot.yaml", "goboot.yml"}
+
+// envSource reads environment variables, decoding JSON values.
+// Empty variables are treated as not set, unless keepEmpty is set: then they
+// override the other sources, so the E getters return ErrEmpty for them and
+// Parse applies the envDefault.
+type envSource struct {
+	keepEmpty bool
+}
+
+// EnvSource returns the source backed by the process environment, see EnvKeepEmpty.
+func EnvSource() Source {
+	keep, _ := strconv.ParseBool(os.Getenv(EnvKeepEmpty))
+	return envSource{keepEmpty: keep}
+}
+
+func (envSource) Name() string {
+	return SourceEnv
+}
+
+func (r envSource) Lookup(name string) (interface{}, bool) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" && !r.keepEmpty {
+		return nil, false
+	}
+	var t interface{}
+	if err := json.Unmarshal([]byte(v), &t); err != nil {
+		return v, true
+	}
+	return t, true
+}
+
+func (r envSource) Keys() []string {
+	var keys []string
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 && (r.keepEmpty || i < len(kv)-1) {
+			keys = append(keys, kv[:i])
+		}
+	}
+	return keys
+}
+
+// mapSource serves values from an in-memory document.
+type mapSource struct {
+	name string
+	m    map[string]interface{}
+
+	sync.RWMutex
+}
+
+// NewMapSource returns a source serving the top level keys of m.
+func NewMapSource(name string, m map[string]interface{}) Source {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	return &mapSource{name: name, m: m}
+}
+
+func (r *mapSource) Name() string {
+	return r.name
+}
+
+func (r *mapSource) Lookup(name string) (interface{}, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	v, ok := r.m[name]
+	return v, ok
+}
+
+func (r *mapSource) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.m)
+}
+
+// set stores value at the dotted key, creating intermediate objects.
+func (r *mapSource) set(key string, value interface{}) {
+	r.Lock()
+	defer r.Unlock()
+
+	p := splitPath(key)
+	m := r.m
+	for _, k := range p[:len(p)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[k] = next
+		}
+		m = next
+	}
+	m[p[len(p)-1]] = value
+}
+
+// Reloader is implemented by sources that can re-read their backing store.
+type Reloader interface {
+	// Modified reports whether the backing store changed since the last load.
+	Modified() bool
+	// Reload re-reads the backing store.
+	Reload() error
+}
+
+// fileSource serves a JSON or YAML config file.
+type fileSource struct {
+	*mapSource
+	path    string
+	modTime time.Time
+}
+
+// NewFileSource loads a JSON or YAML document whose top level keys are
+// setting names. The format is chosen by the file extension.
+// The returned source implements Reloader.
+func NewFileSource(name, path string) (Source, error) {
+	r := &fileSource{
+		mapSource: NewMapSource(name, nil).(*mapSource),
+		path:      path,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileSource) Modified() bool {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.RLock()
+	defer r.RUnlock()
+	return !fi.ModTime().Equal(r.modTime)
+}
+
+func (r *fileSource) Reload() error {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	m, err := decodeDocument(r.path, b)
+	if err != nil {
+		return fmt.Errorf("config file %s: %v", r.path, err)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.m = m
+	r.modTime = fi.ModTime()
+	return nil
+}
+
+func decodeDocument(path string, b []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var t interface{}
+		if err := yaml.Unmarshal(b, &t); err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return make(map[string]interface{}), nil
+		}
+		var ok bool
+		if m, ok = normalizeYaml(t).(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("expected an object at the top level")
+		}
+	default:
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// normalizeYaml converts the map[interface{}]interface{} produced by yaml
+// into the map[string]interface{} produced by encoding/json.
+func normalizeYaml(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprintf("%v", k)] = normalizeYaml(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = normalizeYaml(v)
+		}
+		return t
+	}
+	return v
+}
+
+// configFile returns the config file to load: $GOBOOT_CONFIG or the first of
+// goboot.json, goboot.yaml and goboot.yml found in the working directory.
+func configFile() string {
+	if f := os.Getenv(EnvConfigFile); f != "" {
+		return f
+	}
+	for _, f := range configFiles {
+		if _, err := os.Stat(f); err == nil {
+			return f
+		}
+	}
+	return ""
+}
+
+// profileFile returns the overlay for the profile next to the config file,
+// e.g. goboot-dev.yml for goboot.yml.
+func profileFile(file, profile string) string {
+	if profile == "" {
+		return ""
+	}
+	if file == "" {
+		file = configFiles[0]
+	}
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + profile + ext
+}
+
+// vcapSource serves VCAP_SERVICES and VCAP_APPLICATION from a cfenv.App.
+type vcapSource struct {
+	env func() *cfenv.App
+}
+
+func (r vcapSource) Name() string {
+	return SourceVcap
+}
+
+func (r vcapSource) Lookup(name string) (interface{}, bool) {
+	app := r.env()
+	if app == nil {
+		return nil, false
+	}
+
+	var v interface{}
+	switch name {
+	case "VCAP_SERVICES":
+		if len(app.Services) == 0 {
+			return nil, false
+		}
+		v = app.Services
+	case "VCAP_APPLICATION":
+		if app.Name == "" {
+			return nil, false
+		}
+		v = app
+	default:
+		return nil, false
+	}
+
+	t, err := toGeneric(v)
+	if err != nil {
+		return nil, false
+	}
+	if m, ok := t.(map[string]interface{}); ok && name == "VCAP_APPLICATION" {
+		m["application_name"] = app.Name
+		m["application_version"] = app.Version
+	}
+	return t, true
+}
+
+func (r vcapSource) Keys() []string {
+	var keys []string
+	for _, k := range []string{"VCAP_SERVICES", "VCAP_APPLICATION"} {
+		if _, ok := r.Lookup(k); ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// toGeneric round trips v through JSON so it can be traversed like env values.
+// cfenv.Service has no json tags, so services are converted field by field.
+func toGeneric(v interface{}) (interface{}, error) {
+	if services, ok := v.(cfenv.Services); ok {
+		m := make(map[string]interface{}, len(services))
+		for label, list := range services {
+			var a []interface{}
+			for _, s := range list {
+				a = append(a, map[string]interface{}{
+					"name":        s.Name,
+					"label":       s.Label,
+					"tags":        s.Tags,
+					"plan":        s.Plan,
+					"credentials": s.Credentials,
+				})
+			}
+			m[label] = a
+		}
+		v = m
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var t interface{}
+	err = json.Unmarshal(b, &t)
+	return t, err
+}
+
+// merge overlays src onto dst. Objects are merged key by key, anything else
+// in src replaces dst. The inputs are not modified.
+func merge(dst, src interface{}) interface{} {
+	d, ok := dst.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	s, ok := src.(map[string]interface{})
+	if !ok {
+		return src
+	}
+
+	m := make(map[string]interface{}, len(d)+len(s))
+	for k, v := range d {
+		m[k] = v
+	}
+	for k, v := range s {
+		if old, ok := m[k]; ok {
+			m[k] = merge(old, v)
+		} else {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// flatten collects the dotted paths of all leaf values under prefix.
+func flatten(prefix string, v interface{}, out map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			out[prefix] = t
+		}
+		for k, v := range t {
+			flatten(prefix+"."+k, v, out)
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			out[prefix] = t
+		}
+		for i, v := range t {
+			flatten(fmt.Sprintf("%s.%d", prefix, i), v, out)
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
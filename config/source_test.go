@@ -0,0 +1,99 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLayeredSources(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "goboot")
+	defer os.RemoveAll(dir)
+
+	file, err := NewFileSource(SourceFile, writeFile(t, dir, "goboot.json", `{
+		"goboot_test": {"name": "file", "connection": {"max_open": 5, "max_idle": 2}},
+		"PORT": 9090
+	}`))
+	assert.NoError(t, err)
+	profile, err := NewFileSource(SourceProfile, writeFile(t, dir, "goboot-dev.yml", `
+goboot_test:
+  connection:
+    max_idle: 3
+`))
+	assert.NoError(t, err)
+
+	os.Setenv("goboot_test", `{"name": "env"}`)
+	defer os.Unsetenv("goboot_test")
+
+	s := NewSettings(file, profile, EnvSource())
+	s.SetDefault("goboot_test.orm.enable", true)
+
+	assert.Equal(t, "env", s.GetStringEnv("goboot_test", "name"))
+	assert.Equal(t, 5, s.GetIntEnv("goboot_test", "connection", "max_open"))
+	assert.Equal(t, 3, s.GetIntEnv("goboot_test", "connection", "max_idle"))
+	assert.Equal(t, true, s.GetBoolEnv("goboot_test", "orm", "enable"))
+	assert.Equal(t, "9090", s.GetStringEnv("PORT"))
+
+	assert.Equal(t, SourceEnv, s.Origin("goboot_test", "name"))
+	assert.Equal(t, SourceFile, s.Origin("goboot_test", "connection", "max_open"))
+	assert.Equal(t, SourceProfile, s.Origin("goboot_test", "connection", "max_idle"))
+	assert.Equal(t, SourceDefaults, s.Origin("goboot_test", "orm", "enable"))
+	assert.Equal(t, "", s.Origin("goboot_test", "missing"))
+
+	origins := s.Origins()
+	assert.Equal(t, SourceProfile, origins["goboot_test.connection.max_idle"])
+	assert.Equal(t, SourceFile, origins["PORT"])
+}
+
+func TestEmptyEnv(t *testing.T) {
+	file := NewMapSource(SourceFile, map[string]interface{}{"GOBOOT_TEST_HOST": "file-host"})
+	os.Setenv("GOBOOT_TEST_HOST", "")
+	defer os.Unsetenv("GOBOOT_TEST_HOST")
+
+	s := NewSettings(file, EnvSource())
+	assert.Equal(t, "file-host", s.GetStringEnv("GOBOOT_TEST_HOST"))
+	assert.NotContains(t, EnvSource().Keys(), "GOBOOT_TEST_HOST")
+
+	os.Setenv(EnvKeepEmpty, "true")
+	defer os.Unsetenv(EnvKeepEmpty)
+	s = NewSettings(file, EnvSource())
+	assert.Equal(t, "", s.GetStringEnv("GOBOOT_TEST_HOST"))
+	assert.Equal(t, SourceEnv, s.Origin("GOBOOT_TEST_HOST"))
+	assert.Contains(t, EnvSource().Keys(), "GOBOOT_TEST_HOST")
+}
+
+func TestParseFromSources(t *testing.T) {
+	type config struct {
+		Name    string `env:"goboot_test.name"`
+		MaxOpen int    `env:"goboot_test.connection.max_open"`
+	}
+
+	s := NewSettings(NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_test": map[string]interface{}{
+			"name":       "file",
+			"connection": map[string]interface{}{"max_open": 7},
+		},
+	}))
+
+	cfg := config{}
+	assert.NoError(t, s.Parse(&cfg))
+	assert.Equal(t, "file", cfg.Name)
+	assert.Equal(t, 7, cfg.MaxOpen)
+}
+
+func TestProfileFile(t *testing.T) {
+	assert.Equal(t, "", profileFile("goboot.json", ""))
+	assert.Equal(t, "goboot-dev.json", profileFile("", "dev"))
+	assert.Equal(t, "conf/app-prod.yml", profileFile("conf/app.yml", "prod"))
+}
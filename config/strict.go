@@ -0,0 +1,243 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// In strict mode, GOBOOT_STRICT=warn or GOBOOT_STRICT=fail, the keys of the goboot_*
+// documents are checked against the keys claimed by the parsed and declared structs,
+// so a typo like goboot_postgres.conection.max_open is reported instead of silently
+// leaving a field at zero:
+//
+//	goboot_postgres.conection.max_open from env: unknown key, did you mean goboot_postgres.connection.max_open?
+//
+// Parse checks the documents of the struct it parsed, warning on stderr or failing
+// with the unknown keys. CheckKeys checks every goboot_* document and is meant to run
+// once all packages are initialized, e.g. by web.Run.
+
+// EnvStrict selects the strict mode.
+const EnvStrict = "GOBOOT_STRICT"
+
+// Strict modes.
+const (
+	StrictOff  = ""
+	StrictWarn = "warn"
+	StrictFail = "fail"
+)
+
+const settingPrefix = "goboot_"
+
+// ErrUnknownKey is reported for keys no struct claims
+var ErrUnknownKey = errors.New("unknown key")
+
+// claims holds the keys bound by the parsed structs.
+type claims struct {
+	keys map[string]bool
+}
+
+// Strict returns the strict mode.
+func (r *Settings) Strict() string {
+	r.Lock()
+	defer r.Unlock()
+	return r.strict
+}
+
+// SetStrict sets the strict mode, StrictOff, StrictWarn or StrictFail.
+func (r *Settings) SetStrict(mode string) {
+	r.Lock()
+	defer r.Unlock()
+	r.strict = mode
+}
+
+// claim records the keys bound by a parsed struct.
+func (r *Settings) claim(specs []FieldSpec) {
+	r.explained.Lock()
+	defer r.explained.Unlock()
+	if r.explained.claims.keys == nil {
+		r.explained.claims.keys = make(map[string]bool)
+	}
+	for _, f := range specs {
+		r.explained.claims.keys[f.Key] = true
+	}
+}
+
+// claimed returns the sorted keys claimed by parsed or declared structs.
+func (r *Settings) claimed() []string {
+	keys := make(map[string]bool)
+	r.explained.Lock()
+	for k := range r.explained.claims.keys {
+		keys[k] = true
+	}
+	r.explained.Unlock()
+	for _, d := range Declared() {
+		for _, f := range d.Fields {
+			keys[f.Key] = true
+		}
+	}
+
+	list := make([]string, 0, len(keys))
+	for k := range keys {
+		if !strings.Contains(k, "/") {
+			list = append(list, k)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// CheckKeys reports the keys of every goboot_* document that no parsed or declared
+// struct claims, with the closest claimed key as suggestion.
+func (r *Settings) CheckKeys() error {
+	names := make(map[string]bool)
+	for _, s := range r.sources {
+		for _, k := range s.Keys() {
+			if strings.HasPrefix(k, settingPrefix) {
+				names[k] = true
+			}
+		}
+	}
+	list := make([]string, 0, len(names))
+	for k := range names {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return r.checkKeys(list)
+}
+
+// checkKeys returns a *ParseError listing the unknown keys in the documents names.
+// The per-profile documents in goboot_profiles are checked as well.
+func (r *Settings) checkKeys(names []string) error {
+	claimed := r.claimed()
+	errs := &ParseError{}
+
+	check := func(key, origin string) {
+		if isClaimed(key, claimed) {
+			return
+		}
+		reason := ErrUnknownKey.Error()
+		if s := suggest(key, claimed); s != "" {
+			reason += ", did you mean " + s + "?"
+		}
+		errs.Errors = append(errs.Errors, &FieldError{Key: key, Source: origin, Reason: reason, Err: ErrUnknownKey})
+	}
+
+	for _, name := range names {
+		leaves := make(map[string]interface{})
+		flatten(name, r.merged(name), leaves)
+		keys := sortedKeys(leaves)
+
+		if name == settingProfiles {
+			for _, k := range keys {
+				// goboot_profiles.<profile>.<key>
+				if p := strings.SplitN(k, ".", 3); len(p) == 3 && strings.HasPrefix(p[2], settingPrefix) {
+					check(p[2], r.Origin(name, strings.Split(k, ".")[1:]...))
+				}
+			}
+			continue
+		}
+		for _, k := range keys {
+			check(k, r.Origin(name, strings.Split(k, ".")[1:]...))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// strictCheck checks the documents the fields of a parsed struct belong to.
+func (r *Settings) strictCheck(specs []FieldSpec, err error) error {
+	mode := r.Strict()
+	if mode == StrictOff {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, f := range specs {
+		if name, _ := splitKey(f.Key); strings.HasPrefix(name, settingPrefix) {
+			names[name] = true
+		}
+	}
+	list := make([]string, 0, len(names))
+	for k := range names {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+
+	unknown := r.checkKeys(list)
+	if unknown == nil {
+		return err
+	}
+	if mode != StrictFail {
+		fmt.Fprintf(os.Stderr, "goboot: %v\n", unknown)
+		return err
+	}
+	pe, ok := err.(*ParseError)
+	if !ok {
+		pe = &ParseError{}
+	}
+	pe.Errors = append(pe.Errors, unknown.(*ParseError).Errors...)
+	return pe
+}
+
+// isClaimed reports whether key, an intermediate object or a value within a claimed
+// map or slice is claimed.
+func isClaimed(key string, claimed []string) bool {
+	for _, c := range claimed {
+		if key == c || strings.HasPrefix(key, c+".") || strings.HasPrefix(c, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the claimed key closest to key by edit distance, if close enough.
+func suggest(key string, claimed []string) string {
+	best, min := "", len(key)/4+2
+	for _, c := range claimed {
+		if d := distance(key, c); d < min {
+			best, min = c, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance of a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(v ...int) int {
+	m := v[0]
+	for _, i := range v[1:] {
+		if i < m {
+			m = i
+		}
+	}
+	return m
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrict(t *testing.T) {
+	s := NewSettings(NewMapSource(SourceFile, map[string]interface{}{
+		"goboot_strict": map[string]interface{}{
+			"name":      "db",
+			"conection": map[string]interface{}{"max_open": float64(5)},
+			"tags":      map[string]interface{}{"a": "b"},
+		},
+		"goboot_stirct": map[string]interface{}{"name": "x"},
+		"goboot_profiles": map[string]interface{}{
+			"prod": map[string]interface{}{"goboot_strict": map[string]interface{}{"nam": "y"}},
+		},
+	}))
+
+	type strictEnv struct {
+		Name    string            `env:"goboot_strict.name"`
+		MaxOpen int               `env:"goboot_strict.connection.max_open"`
+		Tags    map[string]string `env:"goboot_strict.tags"`
+	}
+
+	// off by default
+	assert.NoError(t, s.Parse(&strictEnv{}))
+
+	s.SetStrict(StrictWarn)
+	env := strictEnv{}
+	assert.NoError(t, s.Parse(&env))
+	assert.Equal(t, "db", env.Name)
+
+	s.SetStrict(StrictFail)
+	err := s.Parse(&strictEnv{})
+	assert.Error(t, err)
+	assert.Equal(t, "goboot_strict.conection.max_open from file: unknown key, did you mean goboot_strict.connection.max_open?", err.Error())
+	assert.Equal(t, ErrUnknownKey, err.(*ParseError).Errors[0].Err)
+
+	err = s.CheckKeys()
+	assert.Error(t, err)
+	assert.Equal(t, `3 config errors:
+  goboot_strict.nam from file: unknown key, did you mean goboot_strict.name?
+  goboot_stirct.name from file: unknown key, did you mean goboot_strict.name?
+  goboot_strict.conection.max_open from file: unknown key, did you mean goboot_strict.connection.max_open?`, err.Error())
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, distance("abc", "abc"))
+	assert.Equal(t, 1, distance("conection", "connection"))
+	assert.Equal(t, 2, distance("stirct", "strict"))
+	assert.Equal(t, 3, distance("", "abc"))
+}
@@ -0,0 +1,285 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation rules are given as options of the `env` tag or in a companion
+// `validate` tag, separated by commas:
+//
+//	Port    int      `env:"PORT,required,port"`
+//	Level   string   `env:"goboot_logging.level" validate:"oneof=DEBUG INFO WARN ERROR"`
+//	MaxOpen int      `env:"goboot_postgres.connection.max_open" validate:"min=0,max=100"`
+//	Urls    []string `env:"goboot_elastic.urls" validate:"nonempty"`
+//	Name    string   `env:"goboot_postgres.name" validate:"regex=^[a-z][a-z0-9-]*$"`
+//
+// min and max compare numbers and durations by value and strings, slices and maps
+// by length. url requires an absolute URL, port an integer in 1-65535 and nonempty
+// a value that is set and not empty. regex consumes the rest of the tag so the
+// expression may contain commas. Rules other than nonempty only apply to values
+// that are set.
+
+// FieldError describes a struct field that failed to load or validate.
+// It never includes the offending value, which may be a secret.
+type FieldError struct {
+	Field  string // struct field path, e.g. Postgres.Connection.MaxOpen, empty for getters
+	Key    string // settings key, e.g. goboot_postgres.connection.max_open
+	Source string // source that supplied the value, if any
+	Reason string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	where := e.Key
+	if e.Source != "" {
+		where += " from " + e.Source
+	}
+	switch {
+	case where == "":
+		return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+	case e.Field == "":
+		return fmt.Sprintf("%s: %s", where, e.Reason)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Field, where, e.Reason)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError aggregates every field error found by Parse.
+type ParseError struct {
+	Errors []*FieldError
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = "  " + fe.Error()
+	}
+	return fmt.Sprintf("%d config errors:\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+func (e *ParseError) add(field, key, source string, err error, value interface{}) {
+	e.Errors = append(e.Errors, &FieldError{
+		Field:  field,
+		Key:    key,
+		Source: source,
+		Reason: reason(err, value),
+		Err:    err,
+	})
+}
+
+// reason describes err without echoing value. The rule messages leave the value out,
+// the errors of strconv, time and url quote it.
+func reason(err error, value interface{}) string {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err.Error()
+	}
+	msg := err.Error()
+	if s := toString(value); s != "" {
+		msg = strings.Replace(msg, strconv.Quote(s), "***", -1)
+	}
+	return msg
+}
+
+// elementError describes err of a slice or map element by its position, as the
+// errors of strconv and time quote the element.
+func elementError(pos string, err error, item interface{}) error {
+	if err == ErrUnsupportedType {
+		return err
+	}
+	return fmt.Errorf("%s: %s", pos, reason(err, item))
+}
+
+var (
+	// ErrEmpty is reported for nonempty fields without a value
+	ErrEmpty = errors.New("must not be empty")
+)
+
+type rule struct {
+	name string
+	arg  string
+	re   *regexp.Regexp
+}
+
+type rules []rule
+
+func isRule(opt string) bool {
+	name := strings.SplitN(opt, "=", 2)[0]
+	switch name {
+	case "min", "max", "oneof", "regex":
+		return strings.Contains(opt, "=")
+	case "url", "port", "nonempty":
+		return name == opt
+	}
+	return false
+}
+
+// parseRules collects the validation rules from env tag options and the validate tag.
+func parseRules(opts []string, tag string) (rules, error) {
+	var list []string
+	for i, opt := range opts {
+		if strings.HasPrefix(opt, "regex=") {
+			list = append(list, strings.Join(opts[i:], ","))
+			break
+		}
+		if isRule(opt) {
+			list = append(list, opt)
+		}
+	}
+	if tag != "" {
+		parts := strings.Split(tag, ",")
+		for i, opt := range parts {
+			if strings.HasPrefix(opt, "regex=") {
+				list = append(list, strings.Join(parts[i:], ","))
+				break
+			}
+			if !isRule(opt) {
+				return nil, errors.New("Validate rule " + opt + " not supported.")
+			}
+			list = append(list, opt)
+		}
+	}
+
+	var rs rules
+	for _, s := range list {
+		kv := strings.SplitN(s, "=", 2)
+		r := rule{name: kv[0]}
+		if len(kv) == 2 {
+			r.arg = kv[1]
+		}
+		if r.name == "regex" {
+			re, err := regexp.Compile(r.arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex rule: %v", err)
+			}
+			r.re = re
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+func (rs rules) has(name string) bool {
+	for _, r := range rs {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the decoded field value against every rule.
+func (rs rules) validate(field reflect.Value) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	for _, r := range rs {
+		if err := r.check(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r rule) check(v reflect.Value) error {
+	switch r.name {
+	case "nonempty":
+		if isZeroLength(v) {
+			return ErrEmpty
+		}
+	case "min", "max":
+		return r.checkRange(v)
+	case "oneof":
+		s := toString(v.Interface())
+		for _, o := range strings.Fields(r.arg) {
+			if s == o {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", r.arg)
+	case "regex":
+		if !r.re.MatchString(toString(v.Interface())) {
+			return fmt.Errorf("must match %s", r.arg)
+		}
+	case "url":
+		s := toString(v.Interface())
+		if u, ok := v.Interface().(url.URL); ok {
+			s = u.String()
+		}
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+	case "port":
+		p, err := strconv.Atoi(toString(v.Interface()))
+		if err != nil || p < 1 || p > 65535 {
+			return errors.New("must be a port number between 1 and 65535")
+		}
+	}
+	return nil
+}
+
+func isZeroLength(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func (r rule) checkRange(v reflect.Value) error {
+	var n, limit float64
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return fmt.Errorf("%s rule not supported for %s", r.name, v.Type())
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(r.arg)
+		if err != nil {
+			return fmt.Errorf("invalid %s rule: %v", r.name, err)
+		}
+		limit = float64(d)
+	} else {
+		f, err := strconv.ParseFloat(r.arg, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s rule: %s", r.name, r.arg)
+		}
+		limit = f
+	}
+
+	if r.name == "min" && n < limit {
+		return fmt.Errorf("must be at least %s", r.arg)
+	}
+	if r.name == "max" && n > limit {
+		return fmt.Errorf("must be at most %s", r.arg)
+	}
+	return nil
+}
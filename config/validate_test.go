@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRules(t *testing.T) {
+	type config struct {
+		Port    int           `env:"V_PORT,port"`
+		Level   string        `env:"V_LEVEL" validate:"oneof=DEBUG INFO"`
+		MaxOpen int           `env:"V_MAX" validate:"min=1,max=10"`
+		Urls    []string      `env:"V_URLS" validate:"nonempty"`
+		Name    string        `env:"V_NAME" validate:"regex=^[a-z]{1,3}$"`
+		Home    string        `env:"V_HOME,url"`
+		Timeout time.Duration `env:"V_TIMEOUT" validate:"max=1m"`
+		Unset   int           `env:"V_UNSET" validate:"min=1"`
+	}
+
+	env := map[string]string{
+		"V_PORT":    "8080",
+		"V_LEVEL":   "INFO",
+		"V_MAX":     "5",
+		"V_URLS":    "http://a",
+		"V_NAME":    "abc",
+		"V_HOME":    "https://example.com",
+		"V_TIMEOUT": "30s",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg := config{}
+	assert.NoError(t, parse(&cfg))
+
+	bad := map[string]string{
+		"V_PORT":    "70000",
+		"V_LEVEL":   "TRACE",
+		"V_MAX":     "11",
+		"V_URLS":    "",
+		"V_NAME":    "abcd",
+		"V_HOME":    "example.com",
+		"V_TIMEOUT": "2m",
+	}
+	for k, v := range bad {
+		os.Setenv(k, v)
+	}
+
+	err := parse(&config{})
+	assert.Error(t, err)
+	pe, ok := err.(*ParseError)
+	assert.True(t, ok)
+	assert.Equal(t, len(bad), len(pe.Errors))
+
+	fields := map[string]string{}
+	for _, fe := range pe.Errors {
+		fields[fe.Field] = fe.Reason
+	}
+	assert.Equal(t, "must be a port number between 1 and 65535", fields["Port"])
+	assert.Equal(t, "must be one of: DEBUG INFO", fields["Level"])
+	assert.Equal(t, "must be at most 10", fields["MaxOpen"])
+	assert.Equal(t, "must not be empty", fields["Urls"])
+	assert.Equal(t, "must match ^[a-z]{1,3}$", fields["Name"])
+	assert.Equal(t, "must be an absolute URL", fields["Home"])
+	assert.Equal(t, "must be at most 1m", fields["Timeout"])
+}
+
+func TestParseErrorsAggregated(t *testing.T) {
+	type inner struct {
+		Count int `env:"count"`
+	}
+	type config struct {
+		Port     int    `env:"A_PORT"`
+		Secret   string `env:"A_SECRET,required"`
+		Inner    inner  `env:"A_INNER"`
+		Password int    `env:"A_PASSWORD"`
+	}
+
+	os.Setenv("A_PORT", "not-a-port")
+	os.Setenv("A_INNER", `{"count": "many"}`)
+	os.Setenv("A_PASSWORD", "hunter2")
+	defer os.Unsetenv("A_PORT")
+	defer os.Unsetenv("A_INNER")
+	defer os.Unsetenv("A_PASSWORD")
+
+	err := parse(&config{})
+	assert.Error(t, err)
+
+	msg := err.Error()
+	assert.True(t, strings.HasPrefix(msg, "4 config errors:"))
+	assert.Contains(t, msg, "Port (A_PORT from env): invalid syntax")
+	assert.Contains(t, msg, "Secret (A_SECRET): Required value is not set")
+	assert.Contains(t, msg, "Inner.Count (A_INNER.count from env): invalid syntax")
+	assert.NotContains(t, msg, "hunter2")
+	assert.NotContains(t, msg, "not-a-port")
+}
+
+func TestElementErrors(t *testing.T) {
+	type config struct {
+		Timeouts []time.Duration `env:"E_TIMEOUTS"`
+		Ports    map[string]int  `env:"E_PORTS"`
+		Labels   map[string]int  `env:"E_LABELS"`
+	}
+
+	os.Setenv("E_TIMEOUTS", "1s,5s,s3cret")
+	os.Setenv("E_PORTS", "http:80,https:hunter2")
+	os.Setenv("E_LABELS", "a:1,t0ken")
+	defer os.Unsetenv("E_TIMEOUTS")
+	defer os.Unsetenv("E_PORTS")
+	defer os.Unsetenv("E_LABELS")
+
+	err := parse(&config{})
+	assert.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "Timeouts (E_TIMEOUTS from env): element 2: time: invalid duration")
+	assert.Contains(t, msg, `Ports (E_PORTS from env): item "https": invalid syntax`)
+	assert.Contains(t, msg, "Labels (E_LABELS from env): item 1: expected key:value")
+	assert.NotContains(t, msg, "s3cret")
+	assert.NotContains(t, msg, "hunter2")
+	assert.NotContains(t, msg, "t0ken")
+}
+
+func TestErrorReasons(t *testing.T) {
+	type config struct {
+		Size    int           `env:"R_SIZE" validate:"min=1000"`
+		Timeout time.Duration `env:"R_TIMEOUT"`
+	}
+
+	os.Setenv("R_SIZE", "1")
+	os.Setenv("R_TIMEOUT", "forever")
+	defer os.Unsetenv("R_SIZE")
+	defer os.Unsetenv("R_TIMEOUT")
+
+	err := parse(&config{})
+	assert.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "Size (R_SIZE from env): must be at least 1000")
+	assert.Contains(t, msg, "Timeout (R_TIMEOUT from env): time: invalid duration ***")
+	assert.NotContains(t, msg, "forever")
+}
+
+func TestUnsupportedValidateRule(t *testing.T) {
+	type config struct {
+		Var string `env:"VAR" validate:"bogus"`
+	}
+	assert.Error(t, parse(&config{}))
+}
@@ -0,0 +1,271 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// EnvWatch enables watching the config files of AppSettings when set to a
+// polling interval, e.g. GOBOOT_WATCH=30s, see Settings.Watch. Once it is set,
+// SIGHUP triggers a reload too, the only one with GOBOOT_WATCH=0. Otherwise
+// SIGHUP is left to the app.
+const EnvWatch = "GOBOOT_WATCH"
+
+// ChangeFunc is called with the values at the subscribed path before and after a reload.
+type ChangeFunc func(old, new interface{})
+
+// ReloadFunc is called after every reload with the changed keys and the reload error.
+type ReloadFunc func(changed []string, err error)
+
+type subscription struct {
+	path string
+	fn   ChangeFunc
+}
+
+// target is a struct populated by ParseAndWatch.
+type target struct {
+	v  interface{}
+	mu sync.Locker
+}
+
+// watchers holds the subscriptions and parse targets of a Settings.
+type watchers struct {
+	subs    map[int]subscription
+	reloads map[int]ReloadFunc
+	targets []target
+	next    int
+
+	sync.Mutex
+}
+
+// Subscribe registers fn to be called after a reload changed the value at the dotted path,
+// e.g. "goboot_logging.level". The returned func cancels the subscription.
+func (r *Settings) Subscribe(path string, fn ChangeFunc) (cancel func()) {
+	w := &r.watchers
+	w.Lock()
+	defer w.Unlock()
+
+	if w.subs == nil {
+		w.subs = make(map[int]subscription)
+	}
+	id := w.next
+	w.next++
+	w.subs[id] = subscription{path: path, fn: fn}
+
+	return func() {
+		w.Lock()
+		defer w.Unlock()
+		delete(w.subs, id)
+	}
+}
+
+// OnReload registers fn to be called after every reload, e.g. to audit config changes.
+// The returned func cancels the registration.
+func (r *Settings) OnReload(fn ReloadFunc) (cancel func()) {
+	w := &r.watchers
+	w.Lock()
+	defer w.Unlock()
+
+	if w.reloads == nil {
+		w.reloads = make(map[int]ReloadFunc)
+	}
+	id := w.next
+	w.next++
+	w.reloads[id] = fn
+
+	return func() {
+		w.Lock()
+		defer w.Unlock()
+		delete(w.reloads, id)
+	}
+}
+
+// ParseAndWatch parses v like Parse and parses it again after every reload that changed
+// a setting. The new values are decoded into a fresh copy first and only assigned to v,
+// while holding mu if not nil, when the whole struct parsed without errors.
+func (r *Settings) ParseAndWatch(v interface{}, mu sync.Locker) error {
+	if err := r.parse(v); err != nil {
+		return err
+	}
+
+	r.watchers.Lock()
+	defer r.watchers.Unlock()
+	r.watchers.targets = append(r.watchers.targets, target{v: v, mu: mu})
+	return nil
+}
+
+// Reload re-reads all reloadable sources, clears the cache, re-populates the
+// ParseAndWatch targets and notifies subscribers. It returns the sorted dotted keys
+// whose values changed.
+func (r *Settings) Reload() ([]string, error) {
+	r.watchers.Lock()
+	subs := make([]subscription, 0, len(r.watchers.subs))
+	for _, s := range r.watchers.subs {
+		subs = append(subs, s)
+	}
+	targets := append([]target(nil), r.watchers.targets...)
+	reloads := make([]ReloadFunc, 0, len(r.watchers.reloads))
+	for _, fn := range r.watchers.reloads {
+		reloads = append(reloads, fn)
+	}
+	r.watchers.Unlock()
+
+	before := r.snapshot()
+	old := make([]interface{}, len(subs))
+	for i, s := range subs {
+		old[i] = r.resolveKey(s.path)
+	}
+
+	var errs []string
+	for _, s := range r.sources {
+		if rl, ok := s.(Reloader); ok {
+			if err := rl.Reload(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	r.Lock()
+	r.cache = make(map[string]interface{})
+	r.Unlock()
+
+	changed := diff(before, r.snapshot())
+	if len(changed) > 0 {
+		for _, t := range targets {
+			if err := r.repopulate(t); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		for i, s := range subs {
+			if v := r.resolveKey(s.path); !reflect.DeepEqual(old[i], v) {
+				s.fn(old[i], v)
+			}
+		}
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = fmt.Errorf("config reload: %s", strings.Join(errs, "; "))
+	}
+	for _, fn := range reloads {
+		fn(changed, err)
+	}
+	return changed, err
+}
+
+// repopulate parses into a new value of the target's type and swaps it in on success.
+func (r *Settings) repopulate(t target) error {
+	ref := reflect.ValueOf(t.v).Elem()
+	fresh := reflect.New(ref.Type())
+	if err := r.parse(fresh.Interface()); err != nil {
+		return err
+	}
+	if t.mu != nil {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+	}
+	ref.Set(fresh.Elem())
+	return nil
+}
+
+// Watch reloads the settings when a reloadable source is modified, checking every
+// interval, or when the process receives SIGHUP. The returned func stops watching.
+func (r *Settings) Watch(interval time.Duration) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var ticker *time.Ticker
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		ticks = ticker.C
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(hup)
+			if ticker != nil {
+				ticker.Stop()
+			}
+			close(done)
+		})
+	}
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+			case <-ticks:
+				if !r.modified() {
+					continue
+				}
+			}
+			if _, err := r.Reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "goboot: %v\n", err)
+			}
+		}
+	}()
+
+	return stop
+}
+
+func (r *Settings) modified() bool {
+	for _, s := range r.sources {
+		if rl, ok := s.(Reloader); ok && rl.Modified() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveKey resolves a dotted key bypassing the cache.
+func (r *Settings) resolveKey(key string) interface{} {
+	name, path := splitKey(key)
+	return traverse(path, r.resolve(name))
+}
+
+// snapshot flattens every setting known to the sources.
+func (r *Settings) snapshot() map[string]interface{} {
+	names := make(map[string]bool)
+	for _, s := range r.sources {
+		for _, k := range s.Keys() {
+			names[k] = true
+		}
+	}
+	out := make(map[string]interface{})
+	for name := range names {
+		flatten(name, r.resolve(name), out)
+	}
+	return out
+}
+
+func diff(before, after map[string]interface{}) []string {
+	var changed []string
+	for k, v := range before {
+		if nv, ok := after[k]; !ok || !reflect.DeepEqual(v, nv) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "goboot")
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "goboot.json", `{"goboot_test": {"level": "INFO", "pool": {"size": 1}}}`)
+	file, err := NewFileSource(SourceFile, path)
+	assert.NoError(t, err)
+	s := NewSettings(file)
+
+	type config struct {
+		Level string `env:"goboot_test.level"`
+		Size  int    `env:"goboot_test.pool.size,min=1"`
+	}
+	var mu sync.Mutex
+	cfg := config{}
+	assert.NoError(t, s.ParseAndWatch(&cfg, &mu))
+	assert.Equal(t, "INFO", cfg.Level)
+
+	var calls []string
+	s.Subscribe("goboot_test.level", func(old, new interface{}) {
+		calls = append(calls, old.(string)+"->"+new.(string))
+	})
+	cancel := s.Subscribe("goboot_test.pool", func(old, new interface{}) {
+		calls = append(calls, "pool")
+	})
+	cancel()
+	var reloads [][]string
+	s.OnReload(func(changed []string, err error) {
+		reloads = append(reloads, changed)
+	})
+
+	writeFile(t, dir, "goboot.json", `{"goboot_test": {"level": "DEBUG", "pool": {"size": 4}}}`)
+	future := time.Now().Add(time.Second)
+	os.Chtimes(path, future, future)
+	assert.True(t, file.(Reloader).Modified())
+
+	changed, err := s.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"goboot_test.level", "goboot_test.pool.size"}, changed)
+	assert.Equal(t, []string{"INFO->DEBUG"}, calls)
+	assert.Equal(t, [][]string{changed}, reloads)
+	assert.Equal(t, config{Level: "DEBUG", Size: 4}, cfg)
+
+	// an invalid update keeps the last good values
+	writeFile(t, dir, "goboot.json", `{"goboot_test": {"level": "WARN", "pool": {"size": 0}}}`)
+	_, err = s.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, config{Level: "DEBUG", Size: 4}, cfg)
+	assert.Equal(t, "WARN", s.GetStringEnv("goboot_test", "level"))
+}
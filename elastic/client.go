@@ -10,32 +10,51 @@
 //      "enable": false
 //   },
 //   "sniff": {
-//       "enable": false
+//       "enable": false,
 //       "scheme": "http"
 //    }
 // }
+// sniff.enable defaults to true for the staging and prod profiles
 package elastic
 
 import (
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
 	es "gopkg.in/olivere/elastic.v3"
 )
 
 var settings = config.AppSettings()
-var log = logging.Logger()
+var log = logging.Named("elastic")
+
+// SetLogger makes the package log to l instead of the "elastic" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 //TODO add more options?
 type ElasticEnv struct {
-	Urls              []string `env:"goboot_elastic.urls"`
-	HealthcheckEnable bool     `env:"goboot_elastic.healthcheck.enable"`
-	SniffEnable       bool     `env:"goboot_elastic.sniff.enable"`
-	SniffScheme       string   `env:"goboot_elastic.sniff.scheme"`
+	Urls              []string `env:"goboot_elastic.urls" desc:"Elasticsearch node urls"`
+	HealthcheckEnable bool     `env:"goboot_elastic.healthcheck.enable" desc:"check the nodes periodically"`
+	SniffEnable       bool     `env:"goboot_elastic.sniff.enable" desc:"discover the cluster nodes, true for the staging and prod profiles"`
+	SniffScheme       string   `env:"goboot_elastic.sniff.scheme" desc:"scheme of the sniffed node urls, http or https"`
+}
+
+// String hides node urls read from a secret, which may carry credentials.
+func (r ElasticEnv) String() string {
+	return settings.Redact(r)
 }
 
 var client *es.Client
 
 func init() {
+	config.Declare(ElasticEnv{}, "Elasticsearch client")
+	// registered once the client is created, or not, so configured sinks don't wait forever
+	defer logging.RegisterSink(SinkType, newSink)
+
+	settings.SetProfileDefault(config.ProfileStaging, "goboot_elastic.sniff.enable", true)
+	settings.SetProfileDefault(config.ProfileProd, "goboot_elastic.sniff.enable", true)
+
 	env := ElasticEnv{}
 
 	err := settings.Parse(&env)
@@ -46,7 +65,9 @@ func init() {
 
 	log.Debugf("Elastic env: %v", env)
 
-	client = initES(env)
+	if !config.DeclareOnly() {
+		client = initES(env)
+	}
 }
 
 func initES(env ElasticEnv) *es.Client {
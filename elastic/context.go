@@ -0,0 +1,34 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package elastic
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gostones/goboot/logging"
+	es "gopkg.in/olivere/elastic.v3"
+)
+
+// ErrNoClient is returned by the context helpers when the client could not be created.
+var ErrNoClient = errors.New("elastic: no client")
+
+// HealthContext returns the cluster health, logging with the request id carried by ctx,
+// see logging.WithContext.
+func HealthContext(ctx context.Context) (*es.ClusterHealthResponse, error) {
+	l := logging.WithContext(ctx, log)
+	if Client() == nil {
+		l.Error(ErrNoClient)
+		return nil, ErrNoClient
+	}
+
+	health, err := Client().ClusterHealth().DoC(ctx)
+	if err != nil {
+		l.Errorf("Elastic health error: %v", err)
+		return nil, err
+	}
+	l.Debugf("Elastic cluster %s status: %s", health.ClusterName, health.Status)
+	return health, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gostones/goboot/logging"
+	es "gopkg.in/olivere/elastic.v3"
+)
+
+// SinkType is the goboot_logging.sinks type shipping entries to Elasticsearch.
+const SinkType = "elastic"
+
+// sink indexes log entries into daily indices with the bulk API of Client.
+type sink struct {
+	client *es.Client
+	index  string
+}
+
+func newSink(c *logging.SinkConfig) (logging.Sink, error) {
+	if Client() == nil {
+		return nil, ErrNoClient
+	}
+	switch c.Format {
+	case "":
+		c.Format = logging.FormatECS
+	case logging.FormatJSON, logging.FormatECS:
+	default:
+		return nil, fmt.Errorf("format must be %s or %s: %q", logging.FormatJSON, logging.FormatECS, c.Format)
+	}
+	return &sink{client: Client(), index: c.Index}, nil
+}
+
+func (r *sink) Write(records []logging.Record) error {
+	bulk := r.client.Bulk()
+	for _, rec := range records {
+		index := r.index + "-" + rec.Time.UTC().Format("2006.01.02")
+		bulk.Add(es.NewBulkIndexRequest().Index(index).Type("log").Doc(json.RawMessage(rec.Data)))
+	}
+
+	res, err := bulk.Do()
+	if err != nil {
+		return err
+	}
+	if failed := res.Failed(); len(failed) > 0 {
+		reason := ""
+		if failed[0].Error != nil {
+			reason = failed[0].Error.Reason
+		}
+		return fmt.Errorf("%d of %d entries not indexed: %s", len(failed), len(records), reason)
+	}
+	return nil
+}
+
+func (r *sink) Close() error {
+	return nil
+}
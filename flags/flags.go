@@ -0,0 +1,402 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package flags provides feature flags declared in code and configured through
+// config.Settings, with runtime overrides kept in memory or in Redis.
+//
+//	var NewCheckout = flags.Bool("new_checkout", false, "use the new checkout flow")
+//	var Theme = flags.Variant("theme", "light", "color theme", "light", "dark")
+//
+//	if NewCheckout.Enabled(userID) { ... }
+//	theme := Theme.Value(tenantID)
+//
+// A flag is set by a value or a rule in the goboot_flags document:
+//
+//	goboot_flags={
+//	  "new_checkout": {"percent": 25},
+//	  "theme": {"variants": {"light": 80, "dark": 20}},
+//	  "search_v2": true,
+//	  "banner": {"enabled": false, "value": "sale"}
+//	}
+//
+// percent enables a boolean flag, or the value of a variant flag, for that share of
+// ids. variants picks a variant by weight. Ids are hashed with the flag name, so an id
+// always gets the same result for a flag while the rule is unchanged. enabled false
+// turns the flag off. Overrides, set with the admin endpoint or SetOverride, take the
+// same form and precedence over the settings. Keep them in Redis, shared by all
+// instances, with:
+//
+//	goboot_flags_store={"redis": "my-redis"}
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logging.Named("flags")
+
+// SetLogger makes the package log to l instead of the "flags" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
+
+const goboot_flags = "goboot_flags"
+
+// Sources of an evaluated value.
+const (
+	SourceDefault  = "default"
+	SourceSetting  = "setting"
+	SourceOverride = "override"
+)
+
+// Flag is a boolean or multivariate feature flag.
+type Flag struct {
+	name     string
+	desc     string
+	def      interface{}
+	variants []string
+	registry *Registry
+}
+
+// Evaluation is the result of evaluating a flag for an id.
+type Evaluation struct {
+	Flag   string      `json:"flag"`
+	ID     string      `json:"id"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	Rule   string      `json:"rule,omitempty"` // value, percent, variants or disabled
+}
+
+// rule is a flag setting or override.
+type rule struct {
+	Enabled  *bool              `json:"enabled"`
+	Value    interface{}        `json:"value"`
+	Percent  *float64           `json:"percent"`
+	Variants map[string]float64 `json:"variants"`
+}
+
+// Name returns the name of the flag.
+func (f *Flag) Name() string {
+	return f.name
+}
+
+// Desc returns the description of the flag.
+func (f *Flag) Desc() string {
+	return f.desc
+}
+
+// Enabled evaluates a boolean flag for id, a user or tenant.
+// A variant flag is enabled if its value is not the default.
+func (f *Flag) Enabled(id string) bool {
+	v := f.Evaluate(id).Value
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != f.def
+}
+
+// Value evaluates a variant flag for id, a user or tenant.
+func (f *Flag) Value(id string) string {
+	return fmt.Sprintf("%v", f.Evaluate(id).Value)
+}
+
+// Evaluate evaluates the flag for id and logs the result.
+func (f *Flag) Evaluate(id string) Evaluation {
+	e := Evaluation{Flag: f.name, ID: id, Value: f.def, Source: SourceDefault}
+
+	raw, source := f.registry.override(f.name), SourceOverride
+	if raw == nil {
+		raw, source = f.registry.settings.GetEnv(goboot_flags, f.name), SourceSetting
+	}
+	if raw != nil {
+		r, err := toRule(raw)
+		if err != nil {
+			log.Errorf("Flag %s: invalid %s: %v", f.name, source, err)
+		} else if v, rule, ok := f.apply(r, id); ok {
+			e.Value, e.Source, e.Rule = v, source, rule
+		} else {
+			log.Errorf("Flag %s: invalid %s value: %v", f.name, source, v)
+		}
+	}
+
+	log.Debugf("Flag %s=%v for %q (%s %s)", f.name, e.Value, id, e.Source, e.Rule)
+	return e
+}
+
+// apply evaluates r for id. It returns false if the resulting value is not valid for the flag.
+func (f *Flag) apply(r rule, id string) (interface{}, string, bool) {
+	_, isBool := f.def.(bool)
+	off := f.def
+	if isBool {
+		off = false
+	}
+
+	if r.Enabled != nil && !*r.Enabled {
+		return off, "disabled", true
+	}
+
+	if len(r.Variants) > 0 {
+		names := make([]string, 0, len(r.Variants))
+		total := 0.0
+		for name, w := range r.Variants {
+			names = append(names, name)
+			total += w
+		}
+		sort.Strings(names)
+		if total <= 0 {
+			return nil, "variants", false
+		}
+		b, sum := bucket(f.name, id)*total/100, 0.0
+		for _, name := range names {
+			sum += r.Variants[name]
+			if b < sum {
+				v, ok := f.coerce(name)
+				return v, "variants", ok
+			}
+		}
+		v, ok := f.coerce(names[len(names)-1])
+		return v, "variants", ok
+	}
+
+	on := r.Value
+	if on == nil {
+		if !isBool {
+			return nil, "value", false
+		}
+		on = true
+	}
+	v, ok := f.coerce(on)
+	if !ok {
+		return on, "value", false
+	}
+
+	if r.Percent != nil {
+		if bucket(f.name, id) < *r.Percent {
+			return v, "percent", true
+		}
+		return off, "percent", true
+	}
+	return v, "value", true
+}
+
+// coerce converts v to the type of the flag.
+func (f *Flag) coerce(v interface{}) (interface{}, bool) {
+	if _, ok := f.def.(bool); ok {
+		switch t := v.(type) {
+		case bool:
+			return t, true
+		case string:
+			b, err := strconv.ParseBool(t)
+			return b, err == nil
+		}
+		return nil, false
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	if len(f.variants) == 0 {
+		return s, true
+	}
+	for _, variant := range f.variants {
+		if s == variant {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// bucket hashes the flag name and id to a number in [0, 100).
+func bucket(name, id string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + id))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func toRule(v interface{}) (rule, error) {
+	var r rule
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		r.Value = v
+		return r, nil
+	}
+	b, err := json.Marshal(m)
+	if err == nil {
+		err = json.Unmarshal(b, &r)
+	}
+	return r, err
+}
+
+// Registry holds flags and their overrides.
+type Registry struct {
+	settings *config.Settings
+	store    Store
+	ttl      time.Duration
+
+	flags     map[string]*Flag
+	overrides map[string]cached
+
+	sync.Mutex
+}
+
+type cached struct {
+	value interface{}
+	at    time.Time
+}
+
+// NewRegistry creates a registry reading the goboot_flags settings from settings and
+// the overrides from store, caching them for ttl.
+func NewRegistry(settings *config.Settings, store Store, ttl time.Duration) *Registry {
+	return &Registry{
+		settings:  settings,
+		store:     store,
+		ttl:       ttl,
+		flags:     make(map[string]*Flag),
+		overrides: make(map[string]cached),
+	}
+}
+
+// Bool declares a boolean flag.
+func (r *Registry) Bool(name string, def bool, desc string) *Flag {
+	return r.add(&Flag{name: name, desc: desc, def: def})
+}
+
+// Variant declares a multivariate flag. Values other than variants are rejected,
+// unless no variants are given.
+func (r *Registry) Variant(name, def, desc string, variants ...string) *Flag {
+	return r.add(&Flag{name: name, desc: desc, def: def, variants: variants})
+}
+
+func (r *Registry) add(f *Flag) *Flag {
+	f.registry = r
+	r.Lock()
+	defer r.Unlock()
+	r.flags[f.name] = f
+	return f
+}
+
+// Lookup returns the flag name, nil if not declared.
+func (r *Registry) Lookup(name string) *Flag {
+	r.Lock()
+	defer r.Unlock()
+	return r.flags[name]
+}
+
+// Flags returns the declared flags sorted by name.
+func (r *Registry) Flags() []*Flag {
+	r.Lock()
+	defer r.Unlock()
+	list := make([]*Flag, 0, len(r.flags))
+	for _, f := range r.flags {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// SetStore replaces the override store, caching overrides read from it for ttl.
+func (r *Registry) SetStore(store Store, ttl time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.store = store
+	r.ttl = ttl
+	r.overrides = make(map[string]cached)
+}
+
+// SetOverride overrides the flag name with a value or a rule, see the package doc.
+func (r *Registry) SetOverride(name string, value interface{}) error {
+	f := r.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("flag %s not declared", name)
+	}
+	rl, err := toRule(value)
+	if err != nil {
+		return err
+	}
+	if _, _, ok := f.apply(rl, ""); !ok {
+		return fmt.Errorf("flag %s: invalid value %v", name, value)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if err := r.store.Set(name, value); err != nil {
+		return err
+	}
+	r.overrides[name] = cached{value: value, at: time.Now()}
+	log.Infof("Flag %s overridden: %v", name, value)
+	return nil
+}
+
+// ClearOverride removes the override of the flag name.
+func (r *Registry) ClearOverride(name string) error {
+	r.Lock()
+	defer r.Unlock()
+	if err := r.store.Delete(name); err != nil {
+		return err
+	}
+	r.overrides[name] = cached{at: time.Now()}
+	log.Infof("Flag %s override cleared", name)
+	return nil
+}
+
+// override returns the override of the flag name, nil if none. The store is read
+// without holding the lock, and its errors are not cached.
+func (r *Registry) override(name string) interface{} {
+	r.Lock()
+	if c, ok := r.overrides[name]; ok && time.Since(c.at) < r.ttl {
+		r.Unlock()
+		return c.value
+	}
+	store := r.store
+	r.Unlock()
+
+	at := time.Now()
+	v, err := store.Get(name)
+	if err != nil {
+		log.Errorf("Flag %s: override store error: %v", name, err)
+		return nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	// keep the value of a newer read or change
+	if c, ok := r.overrides[name]; r.store == store && (!ok || c.at.Before(at)) {
+		r.overrides[name] = cached{value: v, at: at}
+	}
+	return v
+}
+
+// the registry of the package level functions
+var registry = NewRegistry(config.AppSettings(), NewMemoryStore(), 0)
+
+// Default returns the registry used by the package level functions.
+func Default() *Registry {
+	return registry
+}
+
+// Bool declares a boolean flag in the default registry.
+func Bool(name string, def bool, desc string) *Flag {
+	return registry.Bool(name, def, desc)
+}
+
+// Variant declares a multivariate flag in the default registry.
+func Variant(name, def, desc string, variants ...string) *Flag {
+	return registry.Variant(name, def, desc, variants...)
+}
+
+// Lookup returns the flag name from the default registry, nil if not declared.
+func Lookup(name string) *Flag {
+	return registry.Lookup(name)
+}
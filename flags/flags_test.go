@@ -0,0 +1,160 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRegistry(doc map[string]interface{}) *Registry {
+	s := config.NewSettings(config.NewMapSource(config.SourceFile, map[string]interface{}{"goboot_flags": doc}))
+	return NewRegistry(s, NewMemoryStore(), 0)
+}
+
+func TestBoolFlag(t *testing.T) {
+	r := testRegistry(map[string]interface{}{
+		"on":       true,
+		"off":      map[string]interface{}{"enabled": false},
+		"text":     "true",
+		"rollout":  map[string]interface{}{"percent": float64(25)},
+		"disabled": map[string]interface{}{"enabled": false, "percent": float64(100)},
+	})
+
+	assert.True(t, r.Bool("on", false, "").Enabled("u1"))
+	assert.False(t, r.Bool("off", true, "").Enabled("u1"))
+	assert.True(t, r.Bool("text", false, "").Enabled("u1"))
+	assert.True(t, r.Bool("unset", true, "").Enabled("u1"))
+	assert.False(t, r.Bool("disabled", true, "").Enabled("u1"))
+
+	rollout := r.Bool("rollout", false, "")
+	on := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		if rollout.Enabled(id) {
+			on++
+		}
+		// stable for an id
+		assert.Equal(t, rollout.Enabled(id), rollout.Enabled(id))
+	}
+	assert.InDelta(t, 250, on, 50)
+
+	e := rollout.Evaluate("user-1")
+	assert.Equal(t, SourceSetting, e.Source)
+	assert.Equal(t, "percent", e.Rule)
+}
+
+func TestVariantFlag(t *testing.T) {
+	r := testRegistry(map[string]interface{}{
+		"theme":  map[string]interface{}{"variants": map[string]interface{}{"light": float64(50), "dark": float64(50)}},
+		"color":  "blue",
+		"banner": map[string]interface{}{"value": "sale", "percent": float64(0)},
+	})
+
+	theme := r.Variant("theme", "light", "", "light", "dark")
+	seen := map[string]int{}
+	for i := 0; i < 200; i++ {
+		seen[theme.Value(fmt.Sprintf("tenant-%d", i))]++
+	}
+	assert.Len(t, seen, 2)
+
+	// not a declared variant
+	color := r.Variant("color", "red", "", "red", "green")
+	assert.Equal(t, "red", color.Value("t"))
+	assert.False(t, color.Enabled("t"))
+
+	assert.Equal(t, "none", r.Variant("banner", "none", "").Value("t"))
+}
+
+func TestOverrides(t *testing.T) {
+	r := testRegistry(map[string]interface{}{"beta": false})
+	beta := r.Bool("beta", false, "beta features")
+
+	assert.NoError(t, r.SetOverride("beta", true))
+	e := beta.Evaluate("u")
+	assert.Equal(t, true, e.Value)
+	assert.Equal(t, SourceOverride, e.Source)
+
+	assert.Error(t, r.SetOverride("beta", "maybe"))
+	assert.Error(t, r.SetOverride("missing", true))
+
+	assert.NoError(t, r.ClearOverride("beta"))
+	assert.False(t, beta.Enabled("u"))
+}
+
+// slowStore blocks the reads of the flag slow until release is closed, and fails
+// the reads of the flag broken until it is fixed.
+type slowStore struct {
+	Store
+	release chan struct{}
+	broken  bool
+}
+
+func (r *slowStore) Get(name string) (interface{}, error) {
+	switch {
+	case name == "slow":
+		<-r.release
+	case name == "broken" && r.broken:
+		return nil, errors.New("connection refused")
+	}
+	return r.Store.Get(name)
+}
+
+func TestOverrideStore(t *testing.T) {
+	store := &slowStore{Store: NewMemoryStore(), release: make(chan struct{}), broken: true}
+	r := testRegistry(map[string]interface{}{})
+	r.SetStore(store, time.Hour)
+	slow := r.Bool("slow", false, "slow flag")
+	broken := r.Bool("broken", false, "broken flag")
+	other := r.Bool("other", false, "other flag")
+	assert.NoError(t, store.Set("broken", true))
+
+	done := make(chan bool)
+	go func() {
+		done <- slow.Enabled("u")
+	}()
+
+	// other flags are evaluated while the store is read
+	assert.False(t, other.Enabled("u"))
+	close(store.release)
+	assert.False(t, <-done)
+
+	// store errors are not cached
+	assert.False(t, broken.Enabled("u"))
+	store.broken = false
+	assert.True(t, broken.Enabled("u"))
+}
+
+func TestHandler(t *testing.T) {
+	r := testRegistry(map[string]interface{}{"beta": true})
+	r.Bool("beta", false, "beta features")
+	h := Handler(r)
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `[{"name":"beta","desc":"beta features","default":false,"setting":true}]`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/beta", strings.NewReader(`false`)))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/beta?id=u1", nil))
+	assert.JSONEq(t, `{"flag":"beta","id":"u1","value":false,"source":"override","rule":"value"}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodDelete, "/beta", nil))
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.True(t, r.Lookup("beta").Enabled("u1"))
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
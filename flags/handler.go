@@ -0,0 +1,88 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flags
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gostones/goboot/web"
+)
+
+// The admin endpoint /admin/flags, see web.AdminHandler:
+//
+//	GET    /admin/flags              lists the flags with their settings and overrides
+//	GET    /admin/flags/<name>?id=x  evaluates the flag for id
+//	PUT    /admin/flags/<name>       overrides the flag with the JSON value or rule in the body
+//	DELETE /admin/flags/<name>       clears the override
+
+func init() {
+	web.RegisterAdmin("flags", Handler(registry))
+}
+
+// FlagInfo describes a flag in the admin listing.
+type FlagInfo struct {
+	Name     string      `json:"name"`
+	Desc     string      `json:"desc,omitempty"`
+	Default  interface{} `json:"default"`
+	Variants []string    `json:"variants,omitempty"`
+	Setting  interface{} `json:"setting,omitempty"`
+	Override interface{} `json:"override,omitempty"`
+}
+
+// Handler serves the flags of r, expecting "" or "/<name>" as the path.
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		name := strings.Trim(req.URL.Path, "/")
+		if name == "" {
+			if req.Method != http.MethodGet {
+				res.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			list := []FlagInfo{}
+			for _, f := range r.Flags() {
+				list = append(list, FlagInfo{
+					Name:     f.name,
+					Desc:     f.desc,
+					Default:  f.def,
+					Variants: f.variants,
+					Setting:  r.settings.GetEnv(goboot_flags, f.name),
+					Override: r.override(f.name),
+				})
+			}
+			web.HandleJson(list, res, req)
+			return
+		}
+
+		f := r.Lookup(name)
+		if f == nil {
+			http.Error(res, "flag not declared: "+name, http.StatusNotFound)
+			return
+		}
+
+		var err error
+		switch req.Method {
+		case http.MethodGet:
+			web.HandleJson(f.Evaluate(req.URL.Query().Get("id")), res, req)
+			return
+		case http.MethodPut, http.MethodPost:
+			var v interface{}
+			if err = json.NewDecoder(req.Body).Decode(&v); err == nil {
+				err = r.SetOverride(name, v)
+			}
+		case http.MethodDelete:
+			err = r.ClearOverride(name)
+		default:
+			res.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res.WriteHeader(http.StatusNoContent)
+	})
+}
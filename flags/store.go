@@ -0,0 +1,101 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flags
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gostones/goboot/cf/redis"
+	"github.com/gostones/goboot/config"
+)
+
+// Store keeps flag overrides.
+type Store interface {
+	// Get returns the override of the flag name, nil if none.
+	Get(name string) (interface{}, error)
+	Set(name string, value interface{}) error
+	Delete(name string) error
+}
+
+type FlagsEnv struct {
+	Redis  string        `env:"goboot_flags_store.redis" desc:"name of the redis service keeping the overrides, in memory otherwise"`
+	Prefix string        `env:"goboot_flags_store.prefix" envDefault:"goboot_flags:" desc:"prefix of the redis keys"`
+	TTL    time.Duration `env:"goboot_flags_store.ttl" envDefault:"10s" desc:"how long overrides read from redis are cached"`
+}
+
+// flagsDoc documents the flag settings, which are read per flag.
+type flagsDoc struct {
+	Flags map[string]interface{} `env:"goboot_flags" desc:"flag values and rules by flag name"`
+}
+
+func init() {
+	config.Declare(FlagsEnv{}, "Feature flag overrides")
+	config.Declare(flagsDoc{}, "Feature flags")
+
+	env := FlagsEnv{}
+	if err := config.AppSettings().Parse(&env); err != nil {
+		log.Errorf("Flags init error: %v", err)
+		return
+	}
+	if env.Redis != "" && !config.DeclareOnly() {
+		registry.SetStore(NewRedisStore(redis.NewRedisClient(env.Redis), env.Prefix), env.TTL)
+		log.Infof("Flag overrides kept in redis %s", env.Redis)
+	}
+}
+
+type memoryStore struct {
+	m map[string]interface{}
+	sync.Mutex
+}
+
+// NewMemoryStore returns a store keeping the overrides in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{m: make(map[string]interface{})}
+}
+
+func (r *memoryStore) Get(name string) (interface{}, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.m[name], nil
+}
+
+func (r *memoryStore) Set(name string, value interface{}) error {
+	r.Lock()
+	defer r.Unlock()
+	r.m[name] = value
+	return nil
+}
+
+func (r *memoryStore) Delete(name string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.m, name)
+	return nil
+}
+
+type redisStore struct {
+	client *redis.RedisClient
+	prefix string
+}
+
+// NewRedisStore returns a store keeping the overrides as JSON in redis, under prefix + name.
+func NewRedisStore(client *redis.RedisClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+func (r *redisStore) Get(name string) (interface{}, error) {
+	var v interface{}
+	err := r.client.Get(r.prefix+name, &v)
+	return v, err
+}
+
+func (r *redisStore) Set(name string, value interface{}) error {
+	return r.client.Set(r.prefix+name, value)
+}
+
+func (r *redisStore) Delete(name string) error {
+	return r.client.Delete(r.prefix + name)
+}
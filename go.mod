@@ -12,8 +12,10 @@ require (
 	github.com/gorilla/mux v1.7.2
 	github.com/lib/pq v1.1.1
 	github.com/newrelic/go-agent v2.7.0+incompatible
-	github.com/sirupsen/logrus v1.4.2
+	github.com/sirupsen/logrus v1.6.0
+	github.com/stretchr/objx v0.1.1 // indirect
 	github.com/stretchr/testify v1.2.2
 	github.com/tylerb/graceful v1.2.15
 	gopkg.in/olivere/elastic.v3 v3.0.75
+	gopkg.in/yaml.v2 v2.2.1
 )
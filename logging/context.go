@@ -0,0 +1,64 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields correlating the entries of a request, see web.RequestID.
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldSpanID    = "span_id"
+)
+
+// FieldActor identifies the caller of an admin endpoint, e.g. set by web.AdminHandler.
+const FieldActor = "actor"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying e, see FromContext.
+func NewContext(ctx context.Context, e *logrus.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, e)
+}
+
+// FromContext returns the logger carried by ctx, the standard logger otherwise.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if e, ok := ctx.Value(contextKey{}).(*logrus.Entry); ok {
+		return e
+	}
+	return contextLogger
+}
+
+// WithContext adds the fields of the logger carried by ctx, e.g. the request id, to e.
+// Named loggers keep their level and logger field:
+//
+//	log := logging.WithContext(ctx, log)
+func WithContext(ctx context.Context, e *logrus.Entry) *logrus.Entry {
+	c, ok := ctx.Value(contextKey{}).(*logrus.Entry)
+	if !ok {
+		return e
+	}
+	fields := logrus.Fields{}
+	for k, v := range c.Data {
+		if _, ok := e.Data[k]; !ok {
+			fields[k] = v
+		}
+	}
+	return e.WithFields(fields)
+}
+
+// RequestID returns the request id of the logger carried by ctx, "" if none.
+func RequestID(ctx context.Context) string {
+	if e, ok := ctx.Value(contextKey{}).(*logrus.Entry); ok {
+		if id, ok := e.Data[FieldRequestID].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, Logger(), FromContext(ctx))
+	assert.Equal(t, "", RequestID(ctx))
+
+	e := Logger().WithField(FieldRequestID, "abc")
+	ctx = NewContext(ctx, e)
+	assert.Equal(t, e, FromContext(ctx))
+	assert.Equal(t, "abc", RequestID(ctx))
+
+	l := WithContext(ctx, Named("test/context"))
+	assert.Equal(t, "abc", l.Data[FieldRequestID])
+	assert.Equal(t, "test/context", l.Data["logger"])
+	assert.Equal(t, Named("test/context").Logger, l.Logger)
+}
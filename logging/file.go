@@ -0,0 +1,198 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// suffix of rotated files, e.g. app.log.2017-01-31T15-04-05.000
+const backupFormat = "2006-01-02T15-04-05.000"
+
+// fileSink writes to a file, rotating it by size and age.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	rotate     time.Duration
+	compress   bool
+	maxBackups int
+	maxAge     time.Duration
+
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+func newFileSink(c *SinkConfig) (Sink, error) {
+	if c.Path == "" {
+		return nil, errors.New("path not set")
+	}
+	r := &fileSink{
+		path:       c.Path,
+		maxSize:    int64(c.MaxSize) * 1024 * 1024,
+		rotate:     c.Rotate,
+		compress:   c.Compress,
+		maxBackups: c.MaxBackups,
+		maxAge:     c.MaxAge,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	r.opened = time.Now()
+	return nil
+}
+
+func (r *fileSink) Write(records []Record) error {
+	for i, rec := range records {
+		if r.f != nil && r.due(int64(len(rec.Data))) {
+			if err := r.rotateFile(); err != nil {
+				fmt.Fprintf(os.Stderr, "goboot: log sink file: rotating %s: %v\n", r.path, err)
+			}
+		}
+		// reopened after a failed rotation
+		if r.f == nil {
+			if err := r.open(); err != nil {
+				return &WriteError{Written: i, Err: err}
+			}
+		}
+		n, err := r.f.Write(rec.Data)
+		r.size += int64(n)
+		if err != nil {
+			return &WriteError{Written: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *fileSink) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
+
+// due reports whether the file must be rotated before writing n bytes.
+func (r *fileSink) due(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.maxSize > 0 && r.size+n > r.maxSize {
+		return true
+	}
+	return r.rotate > 0 && time.Since(r.opened) >= r.rotate
+}
+
+// rotateFile renames the file to a backup and opens a new one. The file is reopened
+// if the rename fails, so the writes go on. The compression and pruning of the backups
+// are reported on stderr only.
+func (r *fileSink) rotateFile() error {
+	r.f.Close()
+	r.f = nil
+	backup := r.path + "." + time.Now().Format(backupFormat)
+	rerr := os.Rename(r.path, backup)
+	if err := r.open(); err != nil {
+		return err
+	}
+	if rerr != nil {
+		// retried after another max_size bytes or rotate interval
+		r.size = 0
+		return rerr
+	}
+
+	if r.compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "goboot: log sink file: compressing %s: %v\n", backup, err)
+		}
+	}
+	if err := r.prune(); err != nil {
+		fmt.Fprintf(os.Stderr, "goboot: log sink file: pruning %s: %v\n", r.path, err)
+	}
+	return nil
+}
+
+// prune removes the backups beyond maxBackups or older than maxAge, returning the
+// first error.
+func (r *fileSink) prune() error {
+	if r.maxBackups <= 0 && r.maxAge <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return err
+	}
+	// newest first, the suffixes sort by time
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	var rerr error
+	for i, name := range backups {
+		remove := r.maxBackups > 0 && i >= r.maxBackups
+		if !remove && r.maxAge > 0 {
+			fi, err := os.Stat(name)
+			remove = err == nil && time.Since(fi.ModTime()) > r.maxAge
+		}
+		if remove {
+			if err := os.Remove(name); err != nil && rerr == nil {
+				rerr = err
+			}
+		}
+	}
+	return rerr
+}
+
+// compressFile replaces name by name.gz.
+func compressFile(name string) error {
+	if strings.HasSuffix(name, ".gz") {
+		return nil
+	}
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(out)
+	if _, err := io.Copy(w, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
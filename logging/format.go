@@ -0,0 +1,162 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Log formats selected by goboot_logging.format.
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatECS    = "ecs"
+)
+
+// ECSVersion is the version of the Elastic Common Schema the ecs format follows.
+const ECSVersion = "1.6.0"
+
+type LoggingEnv struct {
+	Level           string            `env:"goboot_logging.level" desc:"DEBUG, INFO, WARN, ERROR, PANIC or FATAL, INFO for the staging and prod profiles, DEBUG otherwise"`
+	Format          string            `env:"goboot_logging.format" envDefault:"text" desc:"text, json, logfmt or ecs"`
+	Fields          map[string]string `env:"goboot_logging.fields" desc:"output names by field name, e.g. {\"msg\": \"message\"}; time, level, msg, func and file name the built-in fields"`
+	TimestampFormat string            `env:"goboot_logging.timestamp_format" desc:"Go time layout of the timestamps, RFC3339 by default"`
+	Caller          bool              `env:"goboot_logging.caller" desc:"report the calling function and file"`
+	Levels          map[string]string `env:"goboot_logging.levels" desc:"levels of named loggers by name or name prefix, e.g. {\"cf/redis\": \"WARN\"}"`
+	Sinks           []SinkConfig      `env:"goboot_logging.sinks" desc:"outputs replacing stdout, see logging.SinkConfig"`
+	RedactDisable   bool              `env:"goboot_logging.redact.disable" desc:"log secrets unmasked"`
+	RedactFields    []string          `env:"goboot_logging.redact.fields" desc:"regular expressions matching names of fields to mask, in addition to the defaults"`
+	RedactPatterns  []string          `env:"goboot_logging.redact.patterns" desc:"regular expressions matching secrets in messages and values, masking the first group if any"`
+
+	SampleFirst      int            `env:"goboot_logging.sampling.first" desc:"entries per level and message template written each period before sampling, no sampling if 0"`
+	SampleThereafter int            `env:"goboot_logging.sampling.thereafter" envDefault:"100" desc:"write every Mth entry after the first ones, none if 0"`
+	SamplePeriod     time.Duration  `env:"goboot_logging.sampling.period" envDefault:"1s" desc:"period the sampling counts are reset"`
+	RateLimits       map[string]int `env:"goboot_logging.rate_limits" desc:"maximum entries per second by level, e.g. {\"INFO\": 100}"`
+	DedupeWindow     time.Duration  `env:"goboot_logging.dedupe.window" desc:"collapse identical entries repeated within this duration into a summary, e.g. 10s, off if 0"`
+}
+
+// ecs names of the built-in fields
+var ecsFields = map[string]string{
+	"time":  "@timestamp",
+	"level": "log.level",
+	"msg":   "message",
+	"func":  "log.origin.function",
+	"file":  "log.origin.file.name",
+}
+
+// NewFormatter returns the formatter for env.Format.
+// The structured formats, json and ecs, always carry the application name
+// and the CF instance index and id read from VCAP_APPLICATION.
+func NewFormatter(env LoggingEnv) (logrus.Formatter, error) {
+	names := make(map[string]string)
+	fields := logrus.Fields{}
+
+	format := strings.ToLower(env.Format)
+	switch format {
+	case FormatECS:
+		for k, v := range ecsFields {
+			names[k] = v
+		}
+		fields["ecs.version"] = ECSVersion
+		fallthrough
+	case FormatJSON:
+		for k, v := range appFields() {
+			fields[k] = v
+		}
+	case "", FormatText, FormatLogfmt:
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", env.Format)
+	}
+	for k, v := range env.Fields {
+		names[k] = v
+	}
+
+	fieldMap := logrus.FieldMap{}
+	rename := make(map[string]string)
+	for k, v := range names {
+		switch k {
+		case "time":
+			fieldMap[logrus.FieldKeyTime] = v
+		case "level":
+			fieldMap[logrus.FieldKeyLevel] = v
+		case "msg":
+			fieldMap[logrus.FieldKeyMsg] = v
+		case "func":
+			fieldMap[logrus.FieldKeyFunc] = v
+		case "file":
+			fieldMap[logrus.FieldKeyFile] = v
+		case "logrus_error":
+			fieldMap[logrus.FieldKeyLogrusError] = v
+		default:
+			rename[k] = v
+		}
+	}
+
+	var f logrus.Formatter
+	switch format {
+	case FormatJSON, FormatECS:
+		f = &logrus.JSONFormatter{
+			TimestampFormat: env.TimestampFormat,
+			FieldMap:        fieldMap,
+		}
+	case FormatLogfmt:
+		f = &logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			QuoteEmptyFields: true,
+			TimestampFormat:  env.TimestampFormat,
+			FieldMap:         fieldMap,
+		}
+	default:
+		f = &logrus.TextFormatter{
+			FullTimestamp:   env.TimestampFormat != "",
+			TimestampFormat: env.TimestampFormat,
+			FieldMap:        fieldMap,
+		}
+	}
+
+	if len(rename) == 0 && len(fields) == 0 {
+		return f, nil
+	}
+	return &fieldFormatter{Formatter: f, rename: rename, fields: fields}, nil
+}
+
+// appFields identifies the application instance on CF.
+func appFields() logrus.Fields {
+	return logrus.Fields{
+		"application_name": settings.GetStringEnv("VCAP_APPLICATION", "application_name"),
+		"instance_index":   settings.GetIntEnv("VCAP_APPLICATION", "instance_index"),
+		"instance_id":      settings.GetStringEnv("VCAP_APPLICATION", "instance_id"),
+	}
+}
+
+// fieldFormatter renames and adds fields before passing entries on.
+type fieldFormatter struct {
+	logrus.Formatter
+	rename map[string]string
+	fields logrus.Fields
+}
+
+func (r *fieldFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data)+len(r.fields))
+	for k, v := range r.fields {
+		data[k] = v
+	}
+	for k, v := range entry.Data {
+		if name, ok := r.rename[k]; ok {
+			k = name
+		}
+		data[k] = v
+	}
+
+	e := *entry
+	e.Data = data
+	return r.Formatter.Format(&e)
+}
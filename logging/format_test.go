@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func format(t *testing.T, env LoggingEnv) string {
+	f, err := NewFormatter(env)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = f
+	l.ReportCaller = env.Caller
+	l.WithField("user", "joe").Info("hello world")
+	return buf.String()
+}
+
+func TestFormatJSON(t *testing.T) {
+	out := format(t, LoggingEnv{Format: FormatJSON, Fields: map[string]string{"msg": "message", "user": "user_name"}})
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &m))
+	assert.Equal(t, "hello world", m["message"])
+	assert.Equal(t, "joe", m["user_name"])
+	assert.Equal(t, "info", m["level"])
+	assert.Contains(t, m, "application_name")
+	assert.Contains(t, m, "instance_index")
+	assert.Contains(t, m, "instance_id")
+	assert.NotContains(t, m, "msg")
+	assert.NotContains(t, m, "user")
+}
+
+func TestFormatECS(t *testing.T) {
+	out := format(t, LoggingEnv{Format: FormatECS, Caller: true})
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &m))
+	assert.Equal(t, "hello world", m["message"])
+	assert.Equal(t, "info", m["log.level"])
+	assert.Equal(t, ECSVersion, m["ecs.version"])
+	assert.Contains(t, m, "@timestamp")
+	assert.Equal(t, "github.com/gostones/goboot/logging.format", m["log.origin.function"])
+	assert.Contains(t, m, "application_name")
+}
+
+func TestFormatLogfmt(t *testing.T) {
+	out := format(t, LoggingEnv{Format: FormatLogfmt, TimestampFormat: time.RFC1123})
+
+	assert.True(t, strings.HasPrefix(out, "time=\""), out)
+	assert.Contains(t, out, `level=info msg="hello world" user=joe`)
+	assert.NotContains(t, out, "application_name")
+}
+
+func TestFormatUnknown(t *testing.T) {
+	_, err := NewFormatter(LoggingEnv{Format: "xml"})
+	assert.Error(t, err)
+}
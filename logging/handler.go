@@ -0,0 +1,115 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LevelRequest is the body of a level change.
+type LevelRequest struct {
+	Level string `json:"level"`
+	// TTL, e.g. "15m", reverts the change after it elapsed.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// LevelHandler serves the log levels with the path relative to its mount point:
+//
+//	GET    /           lists the levels, see Levels
+//	PUT    /[logger]   sets the level of the logger, or the global level, see SetLevel
+//	DELETE /[logger]   removes the level set at runtime, see ResetLevel
+//
+// PUT takes a LevelRequest. Every change is logged with the actor and the request
+// of the logger carried by the request context, and passed to the auditor, see SetAuditor.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		name := strings.Trim(req.URL.Path, "/")
+
+		switch req.Method {
+		case http.MethodGet:
+			writeJson(res, http.StatusOK, Levels())
+		case http.MethodPut, http.MethodPost:
+			var body LevelRequest
+			level, ttl, err := body.parse(req)
+			if err != nil {
+				auditLevel(req, "log_level.set", name, err, map[string]string{"level": body.Level, "ttl": body.TTL})
+				http.Error(res, err.Error(), http.StatusBadRequest)
+				return
+			}
+			SetLevel(name, level, ttl)
+			auditLevel(req, "log_level.set", name, nil, map[string]string{"level": body.Level, "ttl": body.TTL})
+			writeJson(res, http.StatusOK, Levels())
+		case http.MethodDelete:
+			if !ResetLevel(name) {
+				auditLevel(req, "log_level.reset", name, fmt.Errorf("no runtime level"), nil)
+				res.WriteHeader(http.StatusNotFound)
+				return
+			}
+			auditLevel(req, "log_level.reset", name, nil, nil)
+			writeJson(res, http.StatusOK, Levels())
+		default:
+			res.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (r *LevelRequest) parse(req *http.Request) (level logrus.Level, ttl time.Duration, err error) {
+	if err = json.NewDecoder(req.Body).Decode(r); err != nil {
+		return
+	}
+	if level, err = logrus.ParseLevel(r.Level); err != nil {
+		return
+	}
+	if r.TTL != "" {
+		ttl, err = time.ParseDuration(r.TTL)
+	}
+	return
+}
+
+// Auditor records an action of the admin endpoints with its outcome.
+type Auditor func(ctx context.Context, action, target string, err error, details map[string]string)
+
+var auditor Auditor
+
+// SetAuditor sets the auditor of the level changes, called at init by the audit package.
+func SetAuditor(a Auditor) {
+	auditor = a
+}
+
+// auditLevel logs a level change at warning level, to outlast most level changes,
+// and passes it to the auditor.
+func auditLevel(req *http.Request, action, target string, err error, details map[string]string) {
+	e := WithContext(req.Context(), contextLogger).WithFields(logrus.Fields{"action": action, "target": target})
+	if _, ok := e.Data[FieldActor]; !ok {
+		e = e.WithField(FieldActor, "anonymous@"+req.RemoteAddr)
+	}
+	for k, v := range details {
+		if v != "" {
+			e = e.WithField(k, v)
+		}
+	}
+	if err != nil {
+		e.Warnf("Log level change failed: %v", err)
+	} else {
+		e.Warn("Log level changed")
+	}
+
+	if auditor != nil {
+		auditor(req.Context(), action, target, err, details)
+	}
+}
+
+func writeJson(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
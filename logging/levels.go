@@ -0,0 +1,123 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// override is a level set at runtime with SetLevel.
+type override struct {
+	level   logrus.Level
+	expires time.Time
+	timer   *time.Timer
+}
+
+// LevelInfo is the effective level of a logger, "" for the global level.
+type LevelInfo struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+	// Override is set if the level was set at runtime rather than configured.
+	Override bool       `json:"override"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+// Levels returns the global level and those of the named loggers and runtime overrides, sorted by name.
+func Levels() []LevelInfo {
+	registry.Lock()
+	defer registry.Unlock()
+
+	names := map[string]bool{"": true}
+	for name := range registry.loggers {
+		names[name] = true
+	}
+	for name := range registry.overrides {
+		names[name] = true
+	}
+
+	var list []LevelInfo
+	for name := range names {
+		info := LevelInfo{Logger: name, Level: strings.ToUpper(registry.level(name).String())}
+		if o, ok := registry.overrides[name]; ok {
+			info.Override = true
+			if !o.expires.IsZero() {
+				t := o.expires
+				info.Expires = &t
+			}
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Logger < list[j].Logger
+	})
+	return list
+}
+
+// SetLevel sets the level of the named logger and those below it, or the global level if name is "",
+// overriding goboot_logging until ResetLevel is called or ttl, if positive, elapsed.
+func SetLevel(name string, level logrus.Level, ttl time.Duration) {
+	name = strings.Trim(name, "/")
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if o, ok := registry.overrides[name]; ok && o.timer != nil {
+		o.timer.Stop()
+	}
+	o := &override{level: level}
+	if ttl > 0 {
+		o.expires = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() {
+			revert(name, o)
+		})
+	}
+	registry.overrides[name] = o
+	registry.refresh()
+
+	if ttl > 0 {
+		contextLogger.Infof("Log level of %q set to %s for %s", name, level, ttl)
+	} else {
+		contextLogger.Infof("Log level of %q set to %s", name, level)
+	}
+}
+
+// ResetLevel removes the level set with SetLevel for name, reporting whether there was one.
+func ResetLevel(name string) bool {
+	name = strings.Trim(name, "/")
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	o, ok := registry.overrides[name]
+	if !ok {
+		return false
+	}
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	delete(registry.overrides, name)
+	registry.refresh()
+
+	contextLogger.Infof("Log level of %q reset to %s", name, registry.level(name))
+	return true
+}
+
+// revert removes o when its ttl elapsed unless it was replaced meanwhile.
+func revert(name string, o *override) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registry.overrides[name] != o {
+		return
+	}
+	delete(registry.overrides, name)
+	registry.refresh()
+
+	contextLogger.Infof("Log level of %q reverted to %s", name, registry.level(name))
+}
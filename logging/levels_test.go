@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func findLevel(list []LevelInfo, name string) LevelInfo {
+	for _, l := range list {
+		if l.Logger == name {
+			return l
+		}
+	}
+	return LevelInfo{}
+}
+
+func TestSetLevel(t *testing.T) {
+	global := logrus.GetLevel()
+	l := Named("test/levels/a")
+
+	SetLevel("test/levels", logrus.ErrorLevel, 0)
+	assert.Equal(t, logrus.ErrorLevel, l.Logger.GetLevel())
+	info := findLevel(Levels(), "test/levels")
+	assert.True(t, info.Override)
+	assert.Nil(t, info.Expires)
+	assert.Equal(t, "ERROR", findLevel(Levels(), "test/levels/a").Level)
+
+	assert.True(t, ResetLevel("test/levels"))
+	assert.False(t, ResetLevel("test/levels"))
+	assert.Equal(t, global, l.Logger.GetLevel())
+
+	SetLevel("test/levels/a", logrus.PanicLevel, 20*time.Millisecond)
+	assert.NotNil(t, findLevel(Levels(), "test/levels/a").Expires)
+	assert.Equal(t, logrus.PanicLevel, l.Logger.GetLevel())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, global, l.Logger.GetLevel())
+	assert.False(t, findLevel(Levels(), "test/levels/a").Override)
+}
+
+func TestLevelHandler(t *testing.T) {
+	var actions []string
+	SetAuditor(func(ctx context.Context, action, target string, err error, details map[string]string) {
+		actions = append(actions, fmt.Sprintf("%s %s %v", action, target, err))
+	})
+	defer SetAuditor(nil)
+
+	logged := &warnHook{}
+	AddHook(logged)
+	defer RemoveHook(logged)
+
+	h := LevelHandler()
+	l := Named("test/handler")
+	defer ResetLevel("test/handler")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return res
+	}
+
+	res := do(http.MethodPut, "/test/handler", `{"level": "warn", "ttl": "1h"}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, logrus.WarnLevel, l.Logger.GetLevel())
+
+	var list []LevelInfo
+	assert.NoError(t, json.Unmarshal(do(http.MethodGet, "/", "").Body.Bytes(), &list))
+	info := findLevel(list, "test/handler")
+	assert.Equal(t, "WARNING", info.Level)
+	assert.NotNil(t, info.Expires)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/test/handler", `{"level": "loud"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/test/handler", `{"level": "info", "ttl": "soon"}`).Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/test/handler", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/test/handler", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPatch, "/", "").Code)
+
+	assert.Equal(t, []string{
+		"log_level.set test/handler <nil>",
+		"log_level.set test/handler not a valid logrus Level: \"loud\"",
+		"log_level.set test/handler time: invalid duration \"soon\"",
+		"log_level.reset test/handler <nil>",
+		"log_level.reset test/handler no runtime level",
+	}, actions)
+
+	// the changes are logged without an auditor too
+	SetAuditor(nil)
+	logged.fired, logged.data = nil, nil
+	req := httptest.NewRequest(http.MethodPut, "/test/handler", strings.NewReader(`{"level": "error"}`))
+	req = req.WithContext(NewContext(req.Context(), Logger().WithFields(logrus.Fields{FieldRequestID: "req-1", FieldActor: "token@192.0.2.1:1234"})))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	do(http.MethodDelete, "/test/handler", "")
+	assert.Equal(t, []string{"Log level changed", "Log level changed"}, logged.fired)
+	assert.Equal(t, "token@192.0.2.1:1234", logged.data[0][FieldActor])
+	assert.Equal(t, "req-1", logged.data[0][FieldRequestID])
+	assert.Equal(t, "log_level.set", logged.data[0]["action"])
+	assert.Equal(t, "error", logged.data[0]["level"])
+	assert.Equal(t, "anonymous@192.0.2.1:1234", logged.data[1][FieldActor])
+	assert.Equal(t, "log_level.reset", logged.data[1]["action"])
+}
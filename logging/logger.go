@@ -2,52 +2,91 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Usage: var log = logging.ContextLogger
+// Usage: var log = logging.Named("cf/redis")
+// Tests configure the loggers with Init, or create isolated ones with New, see logtest.
 // Setup env JSON value:
 // goboot_logging={
-//   "level": "DEBUG"
+//   "level": "DEBUG",
+//   "format": "json",
+//   "fields": {"msg": "message"},
+//   "timestamp_format": "2006-01-02T15:04:05.000Z07:00",
+//   "caller": false,
+//   "levels": {"cf": "WARN", "util": "ERROR"},
+//   "redact": {"fields": ["(?i)ssn"], "patterns": ["card=(\\d+)"]},
+//   "sampling": {"first": 10, "thereafter": 100, "period": "1s"},
+//   "rate_limits": {"INFO": 1000, "DEBUG": 100},
+//   "dedupe": {"window": "10s"},
+//   "sinks": [
+//     {"type": "stdout"},
+//     {"type": "file", "path": "/var/log/app.log", "max_size": 100, "compress": true, "max_backups": 7},
+//     {"type": "syslog", "network": "tcp", "address": "logs.example.com:514", "level": "WARN"},
+//     {"type": "elastic", "index": "logs", "batch_size": 100, "flush": "5s"}
+//   ]
 // }
 // Logging levels: DEBUG, INFO, WARN, ERROR, PANIC, FATAL
-// default is DEBUG
+// default is INFO for the staging and prod profiles, DEBUG otherwise
 // FATAL will terminate your app
+// Formats: text (default), json, logfmt and ecs (Elastic Common Schema)
+// levels sets the level of named loggers by name or name prefix
+// secrets are masked unless redact.disable is set, see Redactor
+// sampling, rate_limits and dedupe drop entries of floods, see Sampling for the counts
+// sinks replace stdout, each with its own level, format and bounded buffer, see SinkConfig
+// json and ecs entries carry application_name, instance_index and instance_id
+// The settings follow config reloads, see config.Settings.Watch
 package logging
 
 import (
 	"github.com/gostones/goboot/config"
 	"github.com/sirupsen/logrus"
-	"os"
 )
 
 var contextLogger *logrus.Entry
 
+var appName string
+
 const goboot_logging string = "goboot_logging"
 
 var settings = config.AppSettings()
 
 func init() {
-	//Predix logstash only accepts text from stdout for now
-	//logrus.SetFormatter(&logrusrus.JSONFormatter{})
-	logrus.SetFormatter(&logrus.TextFormatter{})
-	logrus.SetOutput(os.Stdout)
+	config.Declare(LoggingEnv{}, "Logging")
+
+	settings.SetProfileDefault(config.ProfileStaging, goboot_logging+".level", "INFO")
+	settings.SetProfileDefault(config.ProfileProd, goboot_logging+".level", "INFO")
+
+	appName = settings.GetStringEnv("VCAP_APPLICATION", "application_name")
+	// drop and redact before the sinks and the added hooks get the entries
+	std.install(logrus.StandardLogger())
 
 	//Logrus has six logging levels: Debug, Info, Warning, Error, Fatal and Panic.
 	//
-	level := logLevel()
-	logrus.SetLevel(level)
+	env, err := initialize(Options{})
 
 	//
-	app_name := settings.GetStringEnv("VCAP_APPLICATION", "application_name")
 	contextLogger = logrus.WithFields(logrus.Fields{
-		"application_name": app_name,
+		"application_name": appName,
 	})
 
 	//
-	contextLogger.Infof("Logrus initialized. log level: %s", level)
+	if err != nil {
+		contextLogger.Errorf("Logging init error: %v", err)
+	}
+	contextLogger.Infof("Logrus initialized. log level: %s format: %s", logrus.GetLevel(), env.Format)
+
+	//pick up changes when the settings are reloaded
+	settings.Subscribe(goboot_logging, func(old, new interface{}) {
+		env, ok, err := reload()
+		if err != nil {
+			contextLogger.Errorf("Logging config error: %v", err)
+		}
+		if ok {
+			contextLogger.Infof("Logging changed. log level: %s format: %s", logrus.GetLevel(), env.Format)
+		}
+	})
 }
 
 //default to debug if env not set
-func logLevel() (level logrus.Level) {
-	l := settings.GetStringEnv(goboot_logging, "level")
+func logLevel(l string) (level logrus.Level) {
 	level, err := logrus.ParseLevel(l)
 	if err != nil {
 		level = logrus.DebugLevel
@@ -55,6 +94,7 @@ func logLevel() (level logrus.Level) {
 	return
 }
 
+// Logger returns the logger of the standard logrus instance, see Named for per package loggers.
 func Logger() *logrus.Entry {
 	return contextLogger
 }
@@ -1,7 +1,11 @@
 package logging
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLog(t *testing.T) {
@@ -19,3 +23,107 @@ func TestLog(t *testing.T) {
 	// Calls os.Exit(1) after logging
 	//log.Fatal("Bye.")
 }
+
+func TestNew(t *testing.T) {
+	var out bytes.Buffer
+	l, err := New(Options{Env: &LoggingEnv{Level: "WARN", Format: FormatLogfmt}, Out: &out})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	l.Info("not written")
+	l.WithField("password", "s3cret!").Warn("written")
+
+	assert.NotContains(t, out.String(), "not written")
+	assert.Contains(t, out.String(), "level=warning msg=written")
+	AssertRedacted(t, out.String(), "s3cret!")
+
+	// the standard logger is not changed
+	assert.NotEqual(t, &out, logrus.StandardLogger().Out)
+}
+
+func TestNewInvalid(t *testing.T) {
+	var out bytes.Buffer
+	l, err := New(Options{Env: &LoggingEnv{Level: "INFO", Format: "xml"}, Out: &out})
+	assert.Error(t, err)
+	defer l.Close()
+
+	l.Info("default format")
+	assert.Contains(t, out.String(), `msg="default format"`)
+}
+
+func TestInvalidSettings(t *testing.T) {
+	settings.SetDefault("goboot_logging.format", FormatJSON)
+	settings.SetDefault("goboot_logging.sampling.period", "bogus")
+	defer func() {
+		settings.SetDefault("goboot_logging.format", nil)
+		settings.SetDefault("goboot_logging.sampling.period", nil)
+		Init(Options{})
+	}()
+	secret := "connecting to postgres://u:hunter22@db/app?password=hunter22"
+
+	// the valid settings and the redaction are applied
+	var out bytes.Buffer
+	assert.Error(t, Init(Options{Out: &out}))
+	Named("test/invalid").Warn(secret)
+	assert.Contains(t, out.String(), `"level":"warning"`)
+	AssertRedacted(t, out.String(), "hunter22")
+
+	out.Reset()
+	l, err := New(Options{Out: &out})
+	assert.Error(t, err)
+	defer l.Close()
+	l.Warn(secret)
+	assert.Contains(t, out.String(), `"level":"warning"`)
+	AssertRedacted(t, out.String(), "hunter22")
+}
+
+// warnHook records the messages and the fields of warnings.
+type warnHook struct {
+	fired []string
+	data  []logrus.Fields
+}
+
+func (r *warnHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (r *warnHook) Fire(e *logrus.Entry) error {
+	r.fired = append(r.fired, e.Message)
+	r.data = append(r.data, e.Data)
+	return nil
+}
+
+func TestInit(t *testing.T) {
+	defer Init(Options{})
+
+	var out bytes.Buffer
+	h := &warnHook{}
+	assert.NoError(t, Init(Options{Env: &LoggingEnv{Level: "INFO", Format: FormatJSON}, Out: &out, Hooks: []logrus.Hook{h}}))
+
+	log := Named("test/init")
+	log.Debug("dropped")
+	log.Info("info")
+	log.Warn("warn")
+
+	assert.NotContains(t, out.String(), "dropped")
+	assert.Contains(t, out.String(), `"msg":"info"`)
+	assert.Equal(t, []string{"warn"}, h.fired)
+
+	// the hooks of the previous call are removed
+	assert.NoError(t, Init(Options{Env: &LoggingEnv{Level: "INFO"}, Out: &out}))
+	log.Warn("again")
+	assert.Equal(t, []string{"warn"}, h.fired)
+}
+
+func TestAddHook(t *testing.T) {
+	h := &warnHook{}
+	AddHook(h)
+
+	log := Named("test/hook")
+	log.Warn("fired")
+	log.Error("other level")
+	RemoveHook(h)
+	log.Warn("removed")
+
+	assert.Equal(t, []string{"fired"}, h.fired)
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// named keeps the loggers returned by Named and the levels configured for them.
+type named struct {
+	loggers map[string]*logrus.Entry
+
+	// configured levels, "" is the global level
+	levels map[string]logrus.Level
+	// runtime levels taking precedence, see SetLevel
+	overrides map[string]*override
+
+	// shared by the standard and the named loggers
+	out       io.Writer
+	formatter logrus.Formatter
+	caller    bool
+
+	sync.Mutex
+}
+
+var registry = named{
+	loggers:   make(map[string]*logrus.Entry),
+	levels:    map[string]logrus.Level{"": logrus.InfoLevel},
+	overrides: make(map[string]*override),
+	out:       os.Stdout,
+	formatter: &sampledFormatter{&logrus.TextFormatter{}},
+}
+
+// Named returns the logger for name, e.g. "cf/redis", adding a logger field to its entries.
+// Its level is configured in goboot_logging.levels by name or by a prefix of
+// slash separated name segments, e.g. "cf" for all cf/... loggers, and
+// defaults to goboot_logging.level. Output, format and hooks are those of the standard logger.
+// The goboot packages keep theirs in a variable their SetLogger replaces, e.g. with
+// a logtest logger in tests.
+func Named(name string) *logrus.Entry {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if e, ok := registry.loggers[name]; ok {
+		return e
+	}
+
+	l := logrus.New()
+	l.Hooks = logrus.StandardLogger().Hooks
+	registry.apply(l)
+	l.SetLevel(registry.level(name))
+
+	e := l.WithFields(logrus.Fields{
+		"application_name": appName,
+		"logger":           name,
+	})
+	registry.loggers[name] = e
+	return e
+}
+
+// level returns the level of the longest prefix of name with a runtime or configured level.
+func (r *named) level(name string) logrus.Level {
+	for p := name; ; {
+		if o, ok := r.overrides[p]; ok {
+			return o.level
+		}
+		if l, ok := r.levels[p]; ok {
+			return l
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			p = ""
+		} else {
+			p = p[:i]
+		}
+	}
+}
+
+// setLevels replaces the configured levels and updates the loggers.
+func (r *named) setLevels(global logrus.Level, levels map[string]string) error {
+	var errs []string
+	m := make(map[string]logrus.Level, len(levels)+1)
+	for name, s := range levels {
+		l, err := logrus.ParseLevel(s)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		m[strings.Trim(name, "/")] = l
+	}
+	m[""] = global
+
+	r.Lock()
+	defer r.Unlock()
+	r.levels = m
+	r.refresh()
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("invalid levels: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// refresh applies the levels to the standard and the named loggers, the lock must be held.
+func (r *named) refresh() {
+	logrus.SetLevel(r.level(""))
+	for name, e := range r.loggers {
+		e.Logger.SetLevel(r.level(name))
+	}
+}
+
+// setOutput changes the output, format and caller reporting of the standard and the named loggers.
+// A nil out or formatter keeps the current one. The formatter skips the dropped entries.
+func (r *named) setOutput(out io.Writer, formatter logrus.Formatter, caller bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if out != nil {
+		r.out = out
+	}
+	if formatter != nil {
+		r.formatter = sampled(formatter)
+	}
+	r.caller = caller
+
+	r.apply(logrus.StandardLogger())
+	for _, e := range r.loggers {
+		r.apply(e.Logger)
+	}
+}
+
+func (r *named) apply(l *logrus.Logger) {
+	l.SetOutput(r.out)
+	l.SetFormatter(r.formatter)
+	l.SetReportCaller(r.caller)
+	l.ExitFunc = exit
+}
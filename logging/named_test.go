@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedLevels(t *testing.T) {
+	defer registry.setLevels(logrus.GetLevel(), nil)
+
+	redis := Named("test/cf/redis")
+	pg := Named("test/cf/postgres")
+	other := Named("test/other")
+	assert.Equal(t, redis, Named("test/cf/redis"))
+
+	err := registry.setLevels(logrus.GetLevel(), map[string]string{
+		"test/cf":        "WARN",
+		"test/cf/redis/": "ERROR",
+		"test/bad":       "LOUD",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "test/bad")
+
+	assert.Equal(t, logrus.ErrorLevel, redis.Logger.GetLevel())
+	assert.Equal(t, logrus.WarnLevel, pg.Logger.GetLevel())
+	assert.Equal(t, logrus.GetLevel(), other.Logger.GetLevel())
+	assert.Equal(t, logrus.WarnLevel, Named("test/cf/blobstore").Logger.GetLevel())
+
+	assert.NoError(t, registry.setLevels(logrus.GetLevel(), nil))
+	assert.Equal(t, logrus.GetLevel(), redis.Logger.GetLevel())
+}
+
+func TestNamedField(t *testing.T) {
+	var buf bytes.Buffer
+	l := Named("test/field")
+	l.Logger.SetOutput(&buf)
+	defer registry.setOutput(nil, nil, false)
+
+	l.Logger.SetLevel(logrus.InfoLevel)
+	l.Info("hello")
+	l.Debug("hidden")
+	assert.Contains(t, buf.String(), "logger=test/field")
+	assert.NotContains(t, buf.String(), "hidden")
+}
@@ -0,0 +1,253 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options configure the standard logger with Init, or an isolated one with New.
+type Options struct {
+	// Env replaces goboot_logging. If nil, the settings are read from config.AppSettings
+	// and Init follows their reloads.
+	Env *LoggingEnv
+	// Out replaces stdout if no sinks are configured.
+	Out io.Writer
+	// Hooks get the entries after sampling and redaction.
+	Hooks []logrus.Hook
+}
+
+func (r Options) env() (LoggingEnv, error) {
+	if r.Env != nil {
+		return *r.Env, nil
+	}
+	env := LoggingEnv{}
+	err := settings.Parse(&env)
+	return env, err
+}
+
+// pipeline holds the hooks of a logger, fired in order: sampling, redaction,
+// sinks and the added hooks.
+type pipeline struct {
+	sampler *sampler
+	redact  *RedactHook
+	sinks   *sinks
+	hooks   *hookList
+}
+
+// the pipeline of the standard and the named loggers
+var std = &pipeline{sampler: sampleHook, redact: redactHook, sinks: hook, hooks: &hookList{}}
+
+func newPipeline() *pipeline {
+	return &pipeline{sampler: &sampler{}, redact: NewRedactHook(defaultRedactor), sinks: &sinks{}, hooks: &hookList{}}
+}
+
+func (r *pipeline) install(l *logrus.Logger) {
+	l.AddHook(r.sampler)
+	l.AddHook(r.redact)
+	l.AddHook(r.sinks)
+	l.AddHook(r.hooks)
+}
+
+// configure applies env to the hooks and returns the formatter and the output of the loggers.
+// The valid settings are applied even if others are not.
+func (r *pipeline) configure(env LoggingEnv, out io.Writer) (logrus.Formatter, io.Writer, error) {
+	rerr := r.configureRedaction(env)
+	perr := r.sampler.configure(env)
+	f, err := NewFormatter(env)
+	if f != nil {
+		f = sampled(f)
+	}
+	serr := r.sinks.configure(env)
+	return f, r.sinks.output(out), firstError(err, perr, serr, rerr)
+}
+
+// configureRedaction applies the default and configured redaction rules.
+// The defaults apply if the configured ones are invalid.
+func (r *pipeline) configureRedaction(env LoggingEnv) error {
+	if env.RedactDisable {
+		r.redact.SetRedactor(nil)
+		return nil
+	}
+	redactor, err := NewRedactor(append(DefaultSecretFields, env.RedactFields...), append(DefaultSecretPatterns, env.RedactPatterns...), settings.SecretValues)
+	if err != nil {
+		redactor, _ = NewRedactor(DefaultSecretFields, DefaultSecretPatterns, settings.SecretValues)
+	}
+	r.redact.SetRedactor(redactor)
+	return err
+}
+
+// close stops the duplicate summaries and closes the sinks.
+func (r *pipeline) close() {
+	r.sampler.configure(LoggingEnv{})
+	r.sinks.close()
+}
+
+// hookList fires the hooks added with AddHook, unless the entry was dropped.
+type hookList struct {
+	hooks []logrus.Hook
+	sync.RWMutex
+}
+
+func (r *hookList) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *hookList) Fire(e *logrus.Entry) error {
+	if hasFlag(e, flagDropped) {
+		return nil
+	}
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, h := range r.hooks {
+		for _, l := range h.Levels() {
+			if l == e.Level {
+				if err := h.Fire(e); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (r *hookList) add(h logrus.Hook) {
+	r.Lock()
+	defer r.Unlock()
+	r.hooks = append(r.hooks, h)
+}
+
+func (r *hookList) remove(h logrus.Hook) {
+	r.Lock()
+	defer r.Unlock()
+	hooks := make([]logrus.Hook, 0, len(r.hooks))
+	for _, e := range r.hooks {
+		if e != h {
+			hooks = append(hooks, e)
+		}
+	}
+	r.hooks = hooks
+}
+
+// AddHook adds a hook to the standard and the named loggers. Unlike logrus.AddHook,
+// it may be called while logging, and the hook gets sampled and redacted entries only.
+// h must be comparable, e.g. a pointer, to be removed.
+func AddHook(h logrus.Hook) {
+	std.hooks.add(h)
+}
+
+// RemoveHook removes a hook added with AddHook.
+func RemoveHook(h logrus.Hook) {
+	std.hooks.remove(h)
+}
+
+var current = struct {
+	opts Options
+	sync.Mutex
+}{}
+
+// Init configures the standard and the named loggers, replacing the hooks of the previous call.
+// It is called at import time with empty options; call it again, e.g. in tests,
+// to control the format and the level. The valid settings are applied even if others are not.
+func Init(opts Options) error {
+	_, err := initialize(opts)
+	return err
+}
+
+func initialize(opts Options) (LoggingEnv, error) {
+	env, perr := opts.env()
+
+	current.Lock()
+	for _, h := range current.opts.Hooks {
+		std.hooks.remove(h)
+	}
+	for _, h := range opts.Hooks {
+		std.hooks.add(h)
+	}
+	current.opts = opts
+	current.Unlock()
+
+	lerr := registry.setLevels(logLevel(env.Level), env.Levels)
+	f, out, err := std.configure(env, opts.Out)
+	registry.setOutput(out, f, env.Caller)
+	return env, firstError(perr, err, lerr)
+}
+
+// reload applies the reloaded settings unless the options of Init replaced them.
+// The valid settings are applied even if others are not.
+func reload() (LoggingEnv, bool, error) {
+	current.Lock()
+	opts := current.opts
+	current.Unlock()
+
+	if opts.Env != nil {
+		return *opts.Env, false, nil
+	}
+	env, err := initialize(opts)
+	return env, true, err
+}
+
+// Instance is an isolated logger, configured by its options only.
+type Instance struct {
+	*logrus.Entry
+	pipeline *pipeline
+}
+
+// New returns a logger independent of the standard one and of config.AppSettings
+// if opts.Env is set. The logger is returned even if some settings are invalid.
+// Close it to write the entries queued for its sinks.
+func New(opts Options) (*Instance, error) {
+	env, perr := opts.env()
+
+	p := newPipeline()
+	for _, h := range opts.Hooks {
+		p.hooks.add(h)
+	}
+	l := logrus.New()
+	p.install(l)
+
+	f, out, err := p.configure(env, opts.Out)
+	if f == nil {
+		f = sampled(&logrus.TextFormatter{})
+	}
+	l.SetFormatter(f)
+	l.SetOutput(out)
+	l.SetReportCaller(env.Caller)
+	l.SetLevel(logLevel(env.Level))
+	l.ExitFunc = func(code int) {
+		p.close()
+		os.Exit(code)
+	}
+
+	return &Instance{
+		Entry:    l.WithField("application_name", appName),
+		pipeline: p,
+	}, firstError(perr, err)
+}
+
+// Sinks returns the counters of the sinks of the logger.
+func (r *Instance) Sinks() []SinkStats {
+	return r.pipeline.sinks.stats()
+}
+
+// Close writes the queued entries and closes the sinks of the logger.
+func (r *Instance) Close() {
+	r.pipeline.close()
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Default redaction rules, extended by goboot_logging.redact.
+var (
+	// DefaultSecretFields matches the names of fields whose values are masked.
+	DefaultSecretFields = []string{`(?i)(pass|secret|token|license|credential|api_?key|private|authorization|cookie)`}
+
+	// DefaultSecretPatterns match secrets in messages and values, masking the first group:
+	// URL userinfo, bearer tokens, key=value and "key": "value" pairs, see config.SecretPatterns.
+	DefaultSecretPatterns = config.SecretPatterns
+)
+
+// secret values shorter than this are not masked, they would mask common text
+const minSecretValue = 4
+
+// how long the secret values of the settings are cached
+const secretValuesTTL = time.Second
+
+// redactHook masks the entries of the standard and the named loggers,
+// with the default rules until the settings are applied.
+var redactHook = NewRedactHook(defaultRedactor)
+
+// defaultRedactor masks diagnostics even if redaction of the logs is disabled.
+var defaultRedactor, _ = NewRedactor(DefaultSecretFields, DefaultSecretPatterns, settings.SecretValues)
+
+// Redact masks the secrets in v like the log entries are, e.g. before returning diagnostics.
+// JSON objects are masked by field name.
+func Redact(v interface{}) interface{} {
+	r := redactHook.Redactor()
+	if r == nil {
+		r = defaultRedactor
+	}
+	return r.Value("", v)
+}
+
+// Redactor masks secrets in log entries.
+type Redactor struct {
+	fields   []*regexp.Regexp
+	patterns []*regexp.Regexp
+	values   func() []string
+
+	cache   []string
+	expires time.Time
+	sync.Mutex
+}
+
+// NewRedactor returns a redactor masking the values of fields whose names match one of fields,
+// the matches of patterns, or their first group if any, and the values returned by values,
+// e.g. config.Settings.SecretValues.
+func NewRedactor(fields, patterns []string, values func() []string) (*Redactor, error) {
+	r := &Redactor{values: values}
+	for _, s := range fields {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		r.fields = append(r.fields, re)
+	}
+	for _, s := range patterns {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// String masks the secrets in s.
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = config.Mask(re, s)
+	}
+	for _, v := range r.secrets() {
+		s = strings.Replace(s, v, config.Redacted, -1)
+	}
+	return s
+}
+
+// Value masks v if the field name is secret, or the secrets in it. JSON objects and arrays
+// are copied with their values masked. Values other than strings and errors are replaced
+// by their redacted string form only if it contains a secret.
+func (r *Redactor) Value(name string, v interface{}) interface{} {
+	for _, re := range r.fields {
+		if re.MatchString(name) {
+			return config.Redacted
+		}
+	}
+
+	switch t := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return v
+	case string:
+		return r.String(t)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = r.Value(k, v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, v := range t {
+			a[i] = r.Value(name, v)
+		}
+		return a
+	case error:
+		if s := t.Error(); r.String(s) != s {
+			return r.String(s)
+		}
+		return v
+	}
+	s := fmt.Sprintf("%+v", v)
+	if masked := r.String(s); masked != s {
+		return masked
+	}
+	return v
+}
+
+// Entry masks the message and the fields of e. The fields are copied as they
+// may be shared with the logger e was derived from.
+func (r *Redactor) Entry(e *logrus.Entry) {
+	e.Message = r.String(e.Message)
+
+	data := make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = r.Value(k, v)
+	}
+	e.Data = data
+}
+
+func (r *Redactor) secrets() []string {
+	if r.values == nil {
+		return nil
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	if now := time.Now(); now.After(r.expires) {
+		var cache []string
+		for _, v := range r.values() {
+			if len(v) >= minSecretValue {
+				cache = append(cache, v)
+			}
+		}
+		r.cache = cache
+		r.expires = now.Add(secretValuesTTL)
+	}
+	return r.cache
+}
+
+// RedactHook masks secrets in entries before they are formatted or passed to later hooks, e.g. the sinks.
+type RedactHook struct {
+	redactor *Redactor
+	sync.RWMutex
+}
+
+// NewRedactHook returns a hook masking secrets with r, nothing if r is nil.
+func NewRedactHook(r *Redactor) *RedactHook {
+	return &RedactHook{redactor: r}
+}
+
+func (r *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *RedactHook) Fire(e *logrus.Entry) error {
+	if hasFlag(e, flagDropped) {
+		return nil
+	}
+	if redactor := r.Redactor(); redactor != nil {
+		redactor.Entry(e)
+	}
+	return nil
+}
+
+// Redactor returns the redactor of the hook.
+func (r *RedactHook) Redactor() *Redactor {
+	r.RLock()
+	defer r.RUnlock()
+	return r.redactor
+}
+
+// SetRedactor replaces the redactor of the hook, nil disables it.
+func (r *RedactHook) SetRedactor(redactor *Redactor) {
+	r.Lock()
+	defer r.Unlock()
+	r.redactor = redactor
+}
+
+// RedactFormatter masks secrets in entries before formatting them,
+// for loggers without a RedactHook.
+type RedactFormatter struct {
+	logrus.Formatter
+	Redactor *Redactor
+}
+
+func (r *RedactFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	e := *entry
+	r.Redactor.Entry(&e)
+	return r.Formatter.Format(&e)
+}
+
+// TestingT is the subset of testing.TB used by AssertRedacted.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertRedacted reports an error to t for each secret found in out, e.g. the captured log output.
+func AssertRedacted(t TestingT, out string, secrets ...string) bool {
+	ok := true
+	for i, s := range secrets {
+		if s != "" && strings.Contains(out, s) {
+			t.Errorf("secret %d of %d leaked in: %s", i+1, len(secrets), out)
+			ok = false
+		}
+	}
+	return ok
+}
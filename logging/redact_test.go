@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor(DefaultSecretFields, append(DefaultSecretPatterns, `card=(\d+)`), func() []string {
+		return []string{"s3cr3t-value", "abc"}
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "postgres://joe:***@db:5432/app", r.String("postgres://joe:pa55@db:5432/app"))
+	assert.Equal(t, "Authorization: Bearer ***", r.String("Authorization: Bearer eyJhbGciOi.x-y"))
+	assert.Equal(t, `{"password": "***", "user": "joe"}`, r.String(`{"password": "hunter2", "user": "joe"}`))
+	assert.Equal(t, "card=*** ok", r.String("card=4111111111111111 ok"))
+	assert.Equal(t, "key is *** abc", r.String("key is s3cr3t-value abc"))
+
+	assert.Equal(t, "***", r.Value("db_password", "anything"))
+	assert.Equal(t, 42, r.Value("count", 42))
+	assert.Equal(t, "failed with ***", r.Value("error", errors.New("failed with s3cr3t-value")))
+	assert.Equal(t, map[string]interface{}{"host": "h", "password": "***", "nested": map[string]interface{}{"token": "***"}},
+		r.Value("service", map[string]interface{}{"host": "h", "password": "p", "nested": map[string]interface{}{"token": "t"}}))
+
+	type env struct{ Uri string }
+	assert.Equal(t, "{Uri:redis://:***@host}", r.Value("env", env{"redis://:pw@host"}))
+	assert.Equal(t, env{"plain"}, r.Value("env", env{"plain"}))
+
+	_, err = NewRedactor([]string{"("}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestRedactHook(t *testing.T) {
+	r, _ := NewRedactor(DefaultSecretFields, DefaultSecretPatterns, nil)
+
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.AddHook(NewRedactHook(r))
+
+	shared := l.WithField("api_key", "k-123456")
+	shared.WithField("uri", "amqp://guest:guest-pw@mq").Infof("connecting with password=%s", "hunter2")
+
+	AssertRedacted(t, buf.String(), "k-123456", "guest-pw", "hunter2")
+	assert.Equal(t, "k-123456", shared.Data["api_key"])
+
+	buf.Reset()
+	l.Hooks = make(logrus.LevelHooks)
+	l.Formatter = &RedactFormatter{Formatter: &logrus.JSONFormatter{}, Redactor: r}
+	l.WithField("token", "t-123456").Info("bearer abcdef")
+	AssertRedacted(t, buf.String(), "t-123456", "abcdef")
+	assert.Contains(t, buf.String(), `"token":"***"`)
+}
+
+func TestRedactHookUnconfigured(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	newPipeline().install(l)
+
+	l.Info("connecting to postgres://u:hunter22@db/app?password=hunter22")
+	AssertRedacted(t, buf.String(), "hunter22")
+}
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestAssertRedacted(t *testing.T) {
+	rec := &recorder{}
+	assert.False(t, AssertRedacted(rec, "user=joe password=hunter2", "hunter2", "other"))
+	assert.Len(t, rec.errors, 1)
+	assert.True(t, AssertRedacted(rec, "user=joe password=***", "hunter2"))
+}
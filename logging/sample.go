@@ -0,0 +1,246 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// variable parts of messages, replaced to find the template of a message
+var variable = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+(\.\d+)?|"[^"]*"|'[^']*'`)
+
+type contextFlag int
+
+const (
+	// the entry is not written
+	flagDropped contextFlag = iota
+	// the entry summarizes duplicates and is not sampled
+	flagSummary
+)
+
+// SamplingStats counts the entries not written by reason.
+type SamplingStats struct {
+	Sampled     uint64 `json:"sampled"`
+	RateLimited uint64 `json:"rate_limited"`
+	Duplicates  uint64 `json:"duplicates"`
+}
+
+// repeat tracks the duplicates of an entry.
+type repeat struct {
+	logger  *logrus.Logger
+	data    logrus.Fields
+	level   logrus.Level
+	message string
+	count   int
+	last    time.Time
+}
+
+// sampler drops entries by sampling, per level rate caps and duplicate suppression.
+// Fatal and panic entries are always written.
+type sampler struct {
+	first      int
+	thereafter int
+	period     time.Duration
+	limits     map[logrus.Level]int
+	window     time.Duration
+
+	counts      map[string]int
+	periodStart time.Time
+	rates       map[logrus.Level]int
+	rateStart   time.Time
+	repeats     map[string]*repeat
+	stop        chan struct{}
+
+	stats SamplingStats
+	sync.Mutex
+}
+
+var sampleHook = &sampler{}
+
+// Sampling returns the counts of entries dropped since the process started.
+func Sampling() SamplingStats {
+	sampleHook.Lock()
+	defer sampleHook.Unlock()
+	return sampleHook.stats
+}
+
+// configure applies the sampling settings of env, keeping the counters.
+func (r *sampler) configure(env LoggingEnv) error {
+	limits := make(map[logrus.Level]int)
+	var errs []string
+	for name, n := range env.RateLimits {
+		l, err := logrus.ParseLevel(name)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		limits[l] = n
+	}
+
+	r.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.first = env.SampleFirst
+	r.thereafter = env.SampleThereafter
+	r.period = env.SamplePeriod
+	if r.period <= 0 {
+		r.period = time.Second
+	}
+	r.limits = limits
+	r.window = env.DedupeWindow
+	r.counts = make(map[string]int)
+	r.rates = make(map[logrus.Level]int)
+	r.repeats = make(map[string]*repeat)
+	if r.window > 0 {
+		r.stop = make(chan struct{})
+		go r.flush(r.window, r.stop)
+	}
+	r.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid rate limits: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (r *sampler) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *sampler) Fire(e *logrus.Entry) error {
+	if e.Level <= logrus.FatalLevel || hasFlag(e, flagSummary) {
+		return nil
+	}
+	if !r.allow(e, time.Now()) {
+		setFlag(e, flagDropped)
+	}
+	return nil
+}
+
+func (r *sampler) allow(e *logrus.Entry, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.window > 0 {
+		name, _ := e.Data["logger"].(string)
+		key := fmt.Sprintf("%d\x00%s\x00%s", e.Level, name, e.Message)
+		if rp, ok := r.repeats[key]; ok && now.Sub(rp.last) < r.window {
+			rp.count++
+			rp.last = now
+			r.stats.Duplicates++
+			return false
+		}
+		data := make(logrus.Fields, len(e.Data))
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		r.repeats[key] = &repeat{logger: e.Logger, data: data, level: e.Level, message: e.Message, last: now}
+	}
+
+	if r.first > 0 {
+		if now.Sub(r.periodStart) >= r.period {
+			r.counts = make(map[string]int)
+			r.periodStart = now
+		}
+		t := fmt.Sprintf("%d\x00%s", e.Level, variable.ReplaceAllString(e.Message, "#"))
+		n := r.counts[t] + 1
+		r.counts[t] = n
+		if n > r.first && (r.thereafter <= 0 || (n-r.first)%r.thereafter != 0) {
+			r.stats.Sampled++
+			return false
+		}
+	}
+
+	if limit, ok := r.limits[e.Level]; ok {
+		if now.Sub(r.rateStart) >= time.Second {
+			r.rates = make(map[logrus.Level]int)
+			r.rateStart = now
+		}
+		r.rates[e.Level]++
+		if r.rates[e.Level] > limit {
+			r.stats.RateLimited++
+			return false
+		}
+	}
+	return true
+}
+
+// flush writes a summary of the duplicates every window until stop is closed.
+func (r *sampler) flush(window time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			for _, rp := range r.summaries(now) {
+				logrus.NewEntry(rp.logger).
+					WithContext(context.WithValue(context.Background(), flagSummary, true)).
+					WithFields(rp.data).
+					Logf(rp.level, "%s (repeated %d times)", rp.message, rp.count)
+			}
+		}
+	}
+}
+
+// summaries returns the entries repeated since the last call and forgets idle ones.
+func (r *sampler) summaries(now time.Time) []repeat {
+	r.Lock()
+	defer r.Unlock()
+
+	var list []repeat
+	for key, rp := range r.repeats {
+		if rp.count > 0 {
+			list = append(list, *rp)
+			rp.count = 0
+		} else if now.Sub(rp.last) >= r.window {
+			delete(r.repeats, key)
+		}
+	}
+	return list
+}
+
+func hasFlag(e *logrus.Entry, f contextFlag) bool {
+	return e.Context != nil && e.Context.Value(f) != nil
+}
+
+func setFlag(e *logrus.Entry, f contextFlag) {
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	e.Context = context.WithValue(ctx, f, true)
+}
+
+// sampledFormatter writes nothing for dropped entries.
+type sampledFormatter struct {
+	logrus.Formatter
+}
+
+func (r *sampledFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if hasFlag(e, flagDropped) {
+		return nil, nil
+	}
+	return r.Formatter.Format(e)
+}
+
+// sampled wraps f in a sampledFormatter unless it is one.
+func sampled(f logrus.Formatter) logrus.Formatter {
+	if _, ok := f.(*sampledFormatter); ok {
+		return f
+	}
+	return &sampledFormatter{f}
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	sync.Mutex
+}
+
+func (r *syncBuffer) Write(p []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *syncBuffer) String() string {
+	r.Lock()
+	defer r.Unlock()
+	return r.buf.String()
+}
+
+func entry(level logrus.Level, msg string) *logrus.Entry {
+	e := logrus.NewEntry(logrus.New())
+	e.Level = level
+	e.Message = msg
+	return e
+}
+
+func TestSampling(t *testing.T) {
+	s := &sampler{}
+	assert.NoError(t, s.configure(LoggingEnv{SampleFirst: 2, SampleThereafter: 3, SamplePeriod: time.Minute}))
+
+	now := time.Now()
+	var allowed []int
+	for i := 1; i <= 10; i++ {
+		if s.allow(entry(logrus.InfoLevel, "Retry count: "+strings.Repeat("9", i)), now) {
+			allowed = append(allowed, i)
+		}
+	}
+	// first 2, then every 3rd
+	assert.Equal(t, []int{1, 2, 5, 8}, allowed)
+	assert.Equal(t, uint64(6), s.stats.Sampled)
+
+	// other templates and levels are counted apart
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "other"), now))
+	assert.True(t, s.allow(entry(logrus.WarnLevel, "Retry count: 1"), now))
+
+	// counts are reset every period
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "Retry count: 1"), now.Add(time.Minute)))
+}
+
+func TestSampledFallback(t *testing.T) {
+	defer Init(Options{})
+	registry.setOutput(nil, &logrus.TextFormatter{}, false)
+
+	// the previous formatter is kept if the format is invalid
+	var out bytes.Buffer
+	env := LoggingEnv{Level: "INFO", Format: "xml", SampleFirst: 1, SampleThereafter: 100, SamplePeriod: time.Minute}
+	assert.Error(t, Init(Options{Env: &env, Out: &out}))
+
+	log := Named("test/fallback")
+	for i := 0; i < 3; i++ {
+		log.Info("flood")
+	}
+	assert.Equal(t, 1, strings.Count(out.String(), "flood"))
+}
+
+func TestRateLimits(t *testing.T) {
+	s := &sampler{}
+	assert.NoError(t, s.configure(LoggingEnv{RateLimits: map[string]int{"info": 2}}))
+	assert.Error(t, s.configure(LoggingEnv{RateLimits: map[string]int{"loud": 2}}))
+	assert.NoError(t, s.configure(LoggingEnv{RateLimits: map[string]int{"info": 2}}))
+
+	now := time.Now()
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "a"), now))
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "b"), now))
+	assert.False(t, s.allow(entry(logrus.InfoLevel, "c"), now))
+	assert.True(t, s.allow(entry(logrus.ErrorLevel, "d"), now))
+	assert.True(t, s.allow(entry(logrus.InfoLevel, "e"), now.Add(time.Second)))
+	assert.Equal(t, uint64(1), s.stats.RateLimited)
+}
+
+func TestDedupe(t *testing.T) {
+	s := &sampler{}
+	defer s.configure(LoggingEnv{})
+	assert.NoError(t, s.configure(LoggingEnv{DedupeWindow: 50 * time.Millisecond}))
+
+	var buf syncBuffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = &sampledFormatter{&logrus.TextFormatter{DisableTimestamp: true}}
+	l.AddHook(s)
+
+	for i := 0; i < 5; i++ {
+		l.WithField("logger", "cf/redis").Error("Failed to create redis connection")
+	}
+	l.Info("other")
+	time.Sleep(80 * time.Millisecond)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, `level=error msg="Failed to create redis connection" logger=cf/redis`, lines[0])
+	assert.Equal(t, `level=info msg=other`, lines[1])
+	assert.Equal(t, `level=error msg="Failed to create redis connection (repeated 4 times)" logger=cf/redis`, lines[2])
+	assert.Equal(t, uint64(4), s.stats.Duplicates)
+
+	// fatal entries are never dropped
+	e := entry(logrus.FatalLevel, "Failed to create redis connection")
+	assert.NoError(t, s.Fire(e))
+	assert.False(t, hasFlag(e, flagDropped))
+}
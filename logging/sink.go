@@ -0,0 +1,445 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sink types built in, others are added with RegisterSink.
+const (
+	SinkStdout = "stdout"
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+)
+
+// how long closing the sinks waits for queued entries to be written
+const closeTimeout = 5 * time.Second
+
+// SinkConfig is an element of goboot_logging.sinks. When sinks are configured, entries are
+// written to them only, so add a stdout sink to keep the default output. Stdout is
+// written to until one of them is live though.
+type SinkConfig struct {
+	Type      string        `env:"type" desc:"stdout, stderr, file, syslog or a type registered with RegisterSink, e.g. elastic"`
+	Level     string        `env:"level" desc:"minimum level written, on top of the level of the logger"`
+	Format    string        `env:"format" desc:"format of the entries, goboot_logging.format otherwise"`
+	Buffer    int           `env:"buffer" envDefault:"1024" desc:"entries queued before new ones are dropped"`
+	BatchSize int           `env:"batch_size" envDefault:"1" desc:"entries written at once"`
+	Flush     time.Duration `env:"flush" envDefault:"1s" desc:"write incomplete batches after this duration"`
+
+	// file
+	Path       string        `env:"path" desc:"file path"`
+	MaxSize    int           `env:"max_size" desc:"rotate the file at this size in megabytes"`
+	Rotate     time.Duration `env:"rotate" desc:"rotate the file at this interval, e.g. 24h"`
+	Compress   bool          `env:"compress" desc:"gzip rotated files"`
+	MaxBackups int           `env:"max_backups" desc:"rotated files kept, all if 0"`
+	MaxAge     time.Duration `env:"max_age" desc:"remove rotated files older than this, e.g. 168h"`
+
+	// syslog
+	Network  string `env:"network" envDefault:"udp" desc:"udp, tcp, unix or unixgram"`
+	Address  string `env:"address" envDefault:"localhost:514" desc:"host:port or socket path of the syslog server"`
+	Facility string `env:"facility" envDefault:"local0" desc:"syslog facility, e.g. user or local0"`
+	Tag      string `env:"tag" desc:"syslog app name, the CF application name otherwise"`
+
+	// elastic
+	Index string `env:"index" envDefault:"logs" desc:"prefix of the daily indices, e.g. logs-2017.01.31"`
+}
+
+// Record is a formatted entry.
+type Record struct {
+	Level logrus.Level
+	Time  time.Time
+	Data  []byte
+}
+
+// Sink writes records. Write and Close are called from a single goroutine.
+type Sink interface {
+	Write(records []Record) error
+	Close() error
+}
+
+// WriteError is returned by sinks that wrote the first records of a batch only.
+type WriteError struct {
+	Written int
+	Err     error
+}
+
+func (e *WriteError) Error() string {
+	return e.Err.Error()
+}
+
+// SinkFactory creates a sink. It may fill in defaults of c, e.g. the format.
+type SinkFactory func(c *SinkConfig) (Sink, error)
+
+// SinkStats counts the entries of a sink.
+type SinkStats struct {
+	Type    string `json:"type"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+	// Pending is set until the factory of the type is registered.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// sinks dispatches entries to the configured sinks.
+type sinks struct {
+	runners []*sinkRunner
+	env     LoggingEnv
+
+	sync.RWMutex
+}
+
+// the sinks of the standard and the named loggers
+var hook = &sinks{}
+
+var factories = struct {
+	m map[string]SinkFactory
+	sync.RWMutex
+}{m: map[string]SinkFactory{
+	SinkStdout: func(c *SinkConfig) (Sink, error) { return writerSink{os.Stdout}, nil },
+	SinkStderr: func(c *SinkConfig) (Sink, error) { return writerSink{os.Stderr}, nil },
+	SinkFile:   newFileSink,
+	SinkSyslog: newSyslogSink,
+}}
+
+// RegisterSink adds a sink type, e.g. the elastic package adds "elastic".
+// Configured sinks of the standard logger start now if they were waiting for it.
+func RegisterSink(name string, factory SinkFactory) {
+	factories.Lock()
+	factories.m[name] = factory
+	factories.Unlock()
+
+	hook.Lock()
+	pending := false
+	for _, r := range hook.runners {
+		pending = pending || r.sink == nil && r.config.Type == name
+	}
+	env := hook.env
+	hook.Unlock()
+
+	if pending {
+		if err := hook.configure(env); err != nil {
+			contextLogger.Errorf("Log sink error: %v", err)
+		}
+	}
+}
+
+// Sinks returns the counters of the sinks of the standard logger.
+func Sinks() []SinkStats {
+	return hook.stats()
+}
+
+func (r *sinks) stats() []SinkStats {
+	r.RLock()
+	defer r.RUnlock()
+
+	var list []SinkStats
+	for _, r := range r.runners {
+		list = append(list, SinkStats{
+			Type:    r.config.Type,
+			Written: atomic.LoadUint64(&r.written),
+			Dropped: atomic.LoadUint64(&r.dropped),
+			Failed:  atomic.LoadUint64(&r.failed),
+			Pending: r.sink == nil,
+		})
+	}
+	return list
+}
+
+func (r *sinks) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *sinks) Fire(entry *logrus.Entry) error {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, s := range r.runners {
+		s.fire(entry)
+	}
+	return nil
+}
+
+// configure replaces the sinks with those of env, closing the current ones.
+// Sinks of types not registered yet wait for RegisterSink.
+func (r *sinks) configure(env LoggingEnv) error {
+	var errs []string
+	var runners []*sinkRunner
+	for i := range env.Sinks {
+		c := env.Sinks[i]
+		s, err := r.newRunner(c, env)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Type, err))
+			continue
+		}
+		runners = append(runners, s)
+	}
+
+	r.Lock()
+	old := r.runners
+	r.runners = runners
+	r.env = env
+	r.Unlock()
+
+	for _, s := range old {
+		s.close()
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("invalid sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// close writes the queued entries and closes the sinks.
+func (r *sinks) close() {
+	r.Lock()
+	old := r.runners
+	r.runners = nil
+	r.Unlock()
+
+	for _, s := range old {
+		s.close()
+	}
+}
+
+// output returns the output of the loggers, out or stdout if out is nil. If sinks are
+// configured, the output is written to only while none of them is live, e.g. waiting
+// for RegisterSink or invalid, so the entries are not lost.
+func (r *sinks) output(out io.Writer) io.Writer {
+	if out == nil {
+		out = os.Stdout
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+	if len(r.env.Sinks) > 0 {
+		return &sinkOutput{sinks: r, out: out}
+	}
+	return out
+}
+
+// live reports whether a sink is written to.
+func (r *sinks) live() bool {
+	r.RLock()
+	defer r.RUnlock()
+	for _, s := range r.runners {
+		if s.sink != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sinkOutput writes to out unless a sink is live.
+type sinkOutput struct {
+	sinks *sinks
+	out   io.Writer
+}
+
+func (r *sinkOutput) Write(b []byte) (int, error) {
+	if r.sinks.live() {
+		return len(b), nil
+	}
+	return r.out.Write(b)
+}
+
+func (r *sinks) newRunner(c SinkConfig, env LoggingEnv) (*sinkRunner, error) {
+	level := logrus.TraceLevel
+	if c.Level != "" {
+		l, err := logrus.ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		level = l
+	}
+
+	s := &sinkRunner{config: c, level: level}
+
+	factories.RLock()
+	factory, ok := factories.m[c.Type]
+	factories.RUnlock()
+	if !ok {
+		// waits for RegisterSink
+		return s, nil
+	}
+	sink, err := factory(&s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	fenv := env
+	if s.config.Format != "" {
+		fenv.Format = s.config.Format
+	}
+	f, err := NewFormatter(fenv)
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+
+	if s.config.Buffer < 1 {
+		s.config.Buffer = 1
+	}
+	if s.config.BatchSize < 1 {
+		s.config.BatchSize = 1
+	}
+	if s.config.Flush <= 0 {
+		s.config.Flush = time.Second
+	}
+	s.sink = sink
+	s.formatter = f
+	s.queue = make(chan Record, s.config.Buffer)
+	s.flush = make(chan chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+	return s, nil
+}
+
+// sinkRunner queues the entries of a sink and writes them in the background.
+type sinkRunner struct {
+	config    SinkConfig
+	level     logrus.Level
+	formatter logrus.Formatter
+	sink      Sink
+
+	queue chan Record
+	flush chan chan struct{}
+	done  chan struct{}
+
+	written uint64
+	dropped uint64
+	failed  uint64
+}
+
+func (r *sinkRunner) fire(entry *logrus.Entry) {
+	if r.sink == nil || entry.Level > r.level || hasFlag(entry, flagDropped) {
+		return
+	}
+	b, err := r.formatter.Format(entry)
+	if err != nil {
+		atomic.AddUint64(&r.failed, 1)
+		return
+	}
+
+	select {
+	case r.queue <- Record{Level: entry.Level, Time: entry.Time, Data: b}:
+	default:
+		atomic.AddUint64(&r.dropped, 1)
+	}
+
+	// the process is about to exit or panic
+	if entry.Level <= logrus.FatalLevel {
+		r.sync()
+	}
+}
+
+func (r *sinkRunner) run() {
+	ticker := time.NewTicker(r.config.Flush)
+	defer ticker.Stop()
+
+	var batch []Record
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.sink.Write(batch); err != nil {
+			written := 0
+			if we, ok := err.(*WriteError); ok {
+				written = we.Written
+			}
+			atomic.AddUint64(&r.written, uint64(written))
+			atomic.AddUint64(&r.failed, uint64(len(batch)-written))
+			fmt.Fprintf(os.Stderr, "goboot: log sink %s: %v\n", r.config.Type, err)
+		} else {
+			atomic.AddUint64(&r.written, uint64(len(batch)))
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case rec, ok := <-r.queue:
+			if !ok {
+				write()
+				r.sink.Close()
+				close(r.done)
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= r.config.BatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case c := <-r.flush:
+			for n := len(r.queue); n > 0; n-- {
+				batch = append(batch, <-r.queue)
+			}
+			write()
+			close(c)
+		}
+	}
+}
+
+// sync waits until the queued records are written.
+func (r *sinkRunner) sync() {
+	c := make(chan struct{})
+	select {
+	case r.flush <- c:
+		select {
+		case <-c:
+		case <-time.After(closeTimeout):
+		}
+	case <-time.After(closeTimeout):
+	}
+}
+
+func (r *sinkRunner) close() {
+	if r.sink == nil {
+		return
+	}
+	close(r.queue)
+	select {
+	case <-r.done:
+	case <-time.After(closeTimeout):
+		fmt.Fprintf(os.Stderr, "goboot: log sink %s: close timed out\n", r.config.Type)
+	}
+}
+
+// exit closes the sinks before exiting, see logrus.Logger.ExitFunc.
+func exit(code int) {
+	std.close()
+	os.Exit(code)
+}
+
+// writerSink writes records to w.
+type writerSink struct {
+	w io.Writer
+}
+
+func (r writerSink) Write(records []Record) error {
+	for _, rec := range records {
+		if _, err := r.w.Write(rec.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r writerSink) Close() error {
+	return nil
+}
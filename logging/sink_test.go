@@ -0,0 +1,306 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type memorySink struct {
+	records []Record
+	block   chan struct{}
+	sync.Mutex
+}
+
+func (r *memorySink) Write(records []Record) error {
+	if r.block != nil {
+		<-r.block
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.records = append(r.records, records...)
+	return nil
+}
+
+func (r *memorySink) Close() error {
+	return nil
+}
+
+func (r *memorySink) lines() []string {
+	r.Lock()
+	defer r.Unlock()
+	var lines []string
+	for _, rec := range r.records {
+		lines = append(lines, strings.TrimSpace(string(rec.Data)))
+	}
+	return lines
+}
+
+func unregisterSinks(names ...string) {
+	factories.Lock()
+	defer factories.Unlock()
+	for _, name := range names {
+		delete(factories.m, name)
+	}
+}
+
+func TestSinkConfig(t *testing.T) {
+	s := config.NewSettings()
+	s.SetDefault("goboot_logging", map[string]interface{}{
+		"sinks": []interface{}{
+			map[string]interface{}{"type": "syslog", "level": "WARN"},
+		},
+	})
+	env := LoggingEnv{}
+	assert.NoError(t, s.Parse(&env))
+	assert.Len(t, env.Sinks, 1)
+	c := env.Sinks[0]
+	assert.Equal(t, "WARN", c.Level)
+	assert.Equal(t, "udp", c.Network)
+	assert.Equal(t, "local0", c.Facility)
+	assert.Equal(t, 1024, c.Buffer)
+	assert.Equal(t, time.Second, c.Flush)
+}
+
+func TestSinks(t *testing.T) {
+	defer unregisterSinks("test/memory")
+	defer hook.configure(LoggingEnv{})
+
+	mem := &memorySink{}
+	env := LoggingEnv{Format: FormatJSON, Sinks: []SinkConfig{
+		{Type: "test/memory", Level: "WARN", Format: FormatLogfmt, Buffer: 10, BatchSize: 2, Flush: time.Hour},
+		{Type: "test/pending"},
+	}}
+	assert.NoError(t, hook.configure(env))
+
+	// the output is written to until a sink is live
+	var out bytes.Buffer
+	w := hook.output(&out)
+	w.Write([]byte("pending\n"))
+	assert.Equal(t, "pending\n", out.String())
+
+	stats := Sinks()
+	assert.True(t, stats[0].Pending)
+	RegisterSink("test/memory", func(c *SinkConfig) (Sink, error) {
+		return mem, nil
+	})
+	stats = Sinks()
+	assert.False(t, stats[0].Pending)
+	assert.True(t, stats[1].Pending)
+
+	w.Write([]byte("live\n"))
+	assert.Equal(t, "pending\n", out.String())
+
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.AddHook(hook)
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	// the batch of two is written right away
+	time.Sleep(50 * time.Millisecond)
+	lines := mem.lines()
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `level=warning msg=warn`)
+	assert.Contains(t, lines[1], `level=error msg=error`)
+
+	// incomplete batches are written on close
+	l.Warn("last")
+	hook.configure(LoggingEnv{})
+	assert.Len(t, mem.lines(), 3)
+	assert.Equal(t, os.Stdout, hook.output(nil))
+}
+
+func TestSinkDropped(t *testing.T) {
+	defer unregisterSinks("test/blocked")
+	defer hook.configure(LoggingEnv{})
+
+	mem := &memorySink{block: make(chan struct{})}
+	RegisterSink("test/blocked", func(c *SinkConfig) (Sink, error) {
+		return mem, nil
+	})
+	assert.NoError(t, hook.configure(LoggingEnv{Sinks: []SinkConfig{{Type: "test/blocked", Buffer: 2, BatchSize: 1, Flush: time.Hour}}}))
+
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.AddHook(hook)
+	for i := 0; i < 10; i++ {
+		l.Info("flood")
+		time.Sleep(time.Millisecond)
+	}
+	close(mem.block)
+
+	stats := Sinks()[0]
+	assert.Equal(t, uint64(7), stats.Dropped)
+}
+
+// partialSink writes the first record of every batch only.
+type partialSink struct{}
+
+func (r partialSink) Write(records []Record) error {
+	return &WriteError{Written: 1, Err: errors.New("disk full")}
+}
+
+func (r partialSink) Close() error {
+	return nil
+}
+
+func TestSinkPartialWrite(t *testing.T) {
+	defer unregisterSinks("test/partial")
+	defer hook.configure(LoggingEnv{})
+
+	RegisterSink("test/partial", func(c *SinkConfig) (Sink, error) {
+		return partialSink{}, nil
+	})
+	assert.NoError(t, hook.configure(LoggingEnv{Sinks: []SinkConfig{{Type: "test/partial", Buffer: 10, BatchSize: 2, Flush: time.Hour}}}))
+
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	l.AddHook(hook)
+	l.Info("first")
+	l.Info("second")
+	time.Sleep(50 * time.Millisecond)
+
+	stats := Sinks()[0]
+	assert.Equal(t, uint64(1), stats.Written)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "app.log")
+	s, err := newFileSink(&SinkConfig{Path: path, MaxSize: 1, Compress: true, MaxBackups: 2})
+	assert.NoError(t, err)
+
+	// 512KB each, every second record rotates
+	rec := Record{Data: []byte(strings.Repeat("x", 512*1024-1) + "\n")}
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, s.Write([]Record{rec}))
+		time.Sleep(2 * time.Millisecond)
+	}
+	assert.NoError(t, s.Close())
+
+	backups, _ := filepath.Glob(path + ".*.gz")
+	assert.Len(t, backups, 2)
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), fi.Size())
+
+	f, err := os.Open(backups[0])
+	assert.NoError(t, err)
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(z)
+	assert.NoError(t, err)
+	assert.Len(t, b, 1024*1024)
+}
+
+func TestFileSinkPruneError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// an expired backup that can't be removed
+	path := filepath.Join(dir, "app.log")
+	stuck := path + ".stuck"
+	assert.NoError(t, os.MkdirAll(filepath.Join(stuck, "dir"), 0755))
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(stuck, old, old))
+
+	s, err := newFileSink(&SinkConfig{Path: path, MaxSize: 1, MaxAge: 24 * time.Hour})
+	assert.NoError(t, err)
+
+	rec := Record{Data: []byte(strings.Repeat("x", 512*1024-1) + "\n")}
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, s.Write([]Record{rec}))
+		time.Sleep(2 * time.Millisecond)
+	}
+	assert.NoError(t, s.Close())
+
+	backups, _ := filepath.Glob(path + ".2*")
+	assert.Len(t, backups, 1)
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), fi.Size())
+}
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	s, err := newSyslogSink(&SinkConfig{Network: "udp", Address: conn.LocalAddr().String(), Facility: "local1", Tag: "my app"})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	ts := time.Date(2017, 1, 31, 15, 4, 5, 0, time.UTC)
+	assert.NoError(t, s.Write([]Record{{Level: logrus.WarnLevel, Time: ts, Data: []byte("msg=hello\n")}}))
+
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(b)
+	assert.NoError(t, err)
+	msg := string(b[:n])
+	assert.True(t, strings.HasPrefix(msg, "<140>1 2017-01-31T15:04:05.000000Z "), msg)
+	assert.Contains(t, msg, " my_app ")
+	assert.True(t, strings.HasSuffix(msg, " - - msg=hello"), msg)
+
+	_, err = newSyslogSink(&SinkConfig{Network: "udp", Address: "localhost:514", Facility: "nope"})
+	assert.Error(t, err)
+}
+
+// failingConn fails the writes after the first n.
+type failingConn struct {
+	net.Conn
+	n int
+}
+
+func (r *failingConn) Write(b []byte) (int, error) {
+	if r.n == 0 {
+		return 0, errors.New("connection reset")
+	}
+	r.n--
+	return len(b), nil
+}
+
+func (r *failingConn) Close() error {
+	return nil
+}
+
+func TestSyslogSinkPartialWrite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	s := &syslogSink{network: "tcp", address: addr, conn: &failingConn{n: 1}}
+	records := []Record{{Data: []byte("one")}, {Data: []byte("two")}}
+
+	// the reconnect fails after the first record
+	werr, ok := s.Write(records).(*WriteError)
+	assert.True(t, ok)
+	assert.Equal(t, 1, werr.Written)
+	assert.Nil(t, s.conn)
+
+	werr, ok = s.Write(records).(*WriteError)
+	assert.True(t, ok)
+	assert.Equal(t, 0, werr.Written)
+	assert.NoError(t, s.Close())
+}
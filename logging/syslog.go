@@ -0,0 +1,164 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RFC 5424 timestamp, at most microseconds
+const syslogTime = "2006-01-02T15:04:05.000000Z07:00"
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSink sends RFC 5424 messages, framed by octet counting on stream connections (RFC 6587).
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	host     string
+	tag      string
+	pid      int
+
+	conn net.Conn
+}
+
+func newSyslogSink(c *SinkConfig) (Sink, error) {
+	facility, ok := facilities[strings.ToLower(c.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown facility: %q", c.Facility)
+	}
+	switch c.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported network: %q", c.Network)
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "-"
+	}
+	tag := c.Tag
+	if tag == "" {
+		tag = appName
+	}
+
+	r := &syslogSink{
+		network:  c.Network,
+		address:  c.Address,
+		facility: facility,
+		host:     header(host, 255),
+		tag:      header(tag, 48),
+		pid:      os.Getpid(),
+	}
+	if err := r.dial(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *syslogSink) dial() error {
+	conn, err := net.DialTimeout(r.network, r.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	return nil
+}
+
+func (r *syslogSink) Write(records []Record) error {
+	for i, rec := range records {
+		if err := r.write(r.message(rec)); err != nil {
+			return &WriteError{Written: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// write sends msg, reconnecting once on error, e.g. after the server restarted.
+func (r *syslogSink) write(msg []byte) error {
+	if r.conn != nil {
+		if err := r.send(msg); err == nil {
+			return nil
+		}
+		r.conn.Close()
+		r.conn = nil
+	}
+	if err := r.dial(); err != nil {
+		return err
+	}
+	return r.send(msg)
+}
+
+func (r *syslogSink) send(msg []byte) error {
+	var err error
+	switch r.network {
+	case "tcp", "unix":
+		_, err = fmt.Fprintf(r.conn, "%d %s", len(msg), msg)
+	default:
+		_, err = r.conn.Write(msg)
+	}
+	return err
+}
+
+func (r *syslogSink) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
+// message formats rec as <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG.
+func (r *syslogSink) message(rec Record) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - - ",
+		r.facility*8+severity(rec.Level), rec.Time.Format(syslogTime), r.host, r.tag, r.pid)
+	b.Write(bytes.TrimRight(rec.Data, "\n"))
+	return b.Bytes()
+}
+
+// severity maps logrus levels to syslog severities.
+func severity(l logrus.Level) int {
+	switch l {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+// header makes s a valid RFC 5424 header field: printable ASCII without spaces, at most n long.
+func header(s string, n int) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > n {
+		b = b[:n]
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package logtest captures log entries in memory for assertions in tests.
+//
+// Usage:
+//
+//	log, rec := logtest.New(t)
+//	util.SetLogger(log.Entry)
+//	...
+//	rec.AssertLogged(logrus.WarnLevel, "retrying", logrus.Fields{"attempt": 2})
+//
+// Capture records the entries of the standard and the named loggers instead.
+package logtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// TestingT is the subset of testing.TB used by the assertions.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Recorder is a hook keeping copies of the entries fired, after sampling and redaction.
+type Recorder struct {
+	t       TestingT
+	entries []logrus.Entry
+	sync.Mutex
+}
+
+// NewRecorder returns a recorder reporting failed assertions to t.
+func NewRecorder(t TestingT) *Recorder {
+	return &Recorder{t: t}
+}
+
+// New returns an isolated logger at trace level writing to the recorder only.
+// The options, if any, replace the defaults; their hooks are kept.
+func New(t TestingT, opts ...logging.Options) (*logging.Instance, *Recorder) {
+	rec := NewRecorder(t)
+	o := logging.Options{Env: &logging.LoggingEnv{Level: "TRACE", Format: logging.FormatText}, Out: ioutil.Discard}
+	if len(opts) > 0 {
+		o = opts[0]
+		if o.Env == nil {
+			o.Env = &logging.LoggingEnv{Level: "TRACE", Format: logging.FormatText}
+		}
+		if o.Out == nil {
+			o.Out = ioutil.Discard
+		}
+	}
+	o.Hooks = append(append([]logrus.Hook(nil), o.Hooks...), rec)
+
+	l, err := logging.New(o)
+	if err != nil {
+		t.Errorf("logtest: %v", err)
+	}
+	return l, rec
+}
+
+// Capture records the entries of the standard and the named loggers until the returned
+// function is called. The level and the output of the loggers are not changed.
+func Capture(t TestingT) (*Recorder, func()) {
+	rec := NewRecorder(t)
+	logging.AddHook(rec)
+	return rec, func() { logging.RemoveHook(rec) }
+}
+
+func (r *Recorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *Recorder) Fire(e *logrus.Entry) error {
+	c := *e
+	c.Data = make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		c.Data[k] = v
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.entries = append(r.entries, c)
+	return nil
+}
+
+// Entries returns the recorded entries, oldest first.
+func (r *Recorder) Entries() []logrus.Entry {
+	r.Lock()
+	defer r.Unlock()
+	return append([]logrus.Entry(nil), r.entries...)
+}
+
+// Reset forgets the recorded entries.
+func (r *Recorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.entries = nil
+}
+
+// Find returns the entries of level whose message contains msg and whose fields
+// include fields. Values are compared by their string form, so 2 matches int64(2).
+func (r *Recorder) Find(level logrus.Level, msg string, fields logrus.Fields) []logrus.Entry {
+	var list []logrus.Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Message, msg) && match(e.Data, fields) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// AssertLogged reports an error unless an entry matches, see Find.
+func (r *Recorder) AssertLogged(level logrus.Level, msg string, fields logrus.Fields) bool {
+	if len(r.Find(level, msg, fields)) > 0 {
+		return true
+	}
+	r.t.Errorf("no %s entry with message %q and fields %v in:\n%s", level, msg, fields, r)
+	return false
+}
+
+// AssertNotLogged reports an error if an entry matches, see Find.
+func (r *Recorder) AssertNotLogged(level logrus.Level, msg string, fields logrus.Fields) bool {
+	found := r.Find(level, msg, fields)
+	if len(found) == 0 {
+		return true
+	}
+	r.t.Errorf("unexpected %s entry with message %q and fields %v: %s %v", level, msg, fields, found[0].Message, found[0].Data)
+	return false
+}
+
+// AssertRedacted reports an error for each secret found in the messages or the fields.
+func (r *Recorder) AssertRedacted(secrets ...string) bool {
+	return logging.AssertRedacted(r.t, r.String(), secrets...)
+}
+
+// String lists the recorded entries, one per line.
+func (r *Recorder) String() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		fmt.Fprintf(&b, "%s %q %v\n", e.Level, e.Message, e.Data)
+	}
+	return b.String()
+}
+
+func match(data, fields logrus.Fields) bool {
+	for k, v := range fields {
+		d, ok := data[k]
+		if !ok || fmt.Sprint(d) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
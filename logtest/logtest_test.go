@@ -0,0 +1,60 @@
+package logtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// failures records the errors of failed assertions.
+type failures []string
+
+func (r *failures) Errorf(format string, args ...interface{}) {
+	*r = append(*r, fmt.Sprintf(format, args...))
+}
+
+func TestNew(t *testing.T) {
+	var f failures
+	l, rec := New(&f)
+	defer l.Close()
+
+	l.WithFields(logrus.Fields{"attempt": 2, "token": "abcd1234"}).Warn("retrying after 1s")
+	l.Trace("traced")
+
+	assert.True(t, rec.AssertLogged(logrus.WarnLevel, "retrying", logrus.Fields{"attempt": "2"}))
+	assert.True(t, rec.AssertLogged(logrus.TraceLevel, "traced", nil))
+	assert.True(t, rec.AssertNotLogged(logrus.ErrorLevel, "", nil))
+	assert.True(t, rec.AssertRedacted("abcd1234"))
+	assert.Empty(t, f)
+
+	assert.False(t, rec.AssertLogged(logrus.WarnLevel, "retrying", logrus.Fields{"attempt": 3}))
+	assert.False(t, rec.AssertNotLogged(logrus.WarnLevel, "retrying", nil))
+	assert.Len(t, f, 2)
+
+	rec.Reset()
+	assert.Empty(t, rec.Entries())
+}
+
+func TestNewOptions(t *testing.T) {
+	l, rec := New(t, logging.Options{Env: &logging.LoggingEnv{Level: "ERROR"}})
+	defer l.Close()
+
+	l.Warn("below the level")
+	l.Error("failed")
+
+	rec.AssertNotLogged(logrus.WarnLevel, "below", nil)
+	rec.AssertLogged(logrus.ErrorLevel, "failed", nil)
+}
+
+func TestCapture(t *testing.T) {
+	rec, restore := Capture(t)
+	logging.Named("test/capture").Error("captured")
+	restore()
+	logging.Named("test/capture").Error("not captured")
+
+	rec.AssertLogged(logrus.ErrorLevel, "captured", logrus.Fields{"logger": "test/capture"})
+	assert.Len(t, rec.Entries(), 1)
+}
@@ -6,9 +6,10 @@
 // Setup env JSON value:
 // goboot_newrelic={
 //   "enable": true,
-//   "name: "Your_App_Name",
-//   "license: "__YOUR_NEW_RELIC_LICENSE_KEY__"
+//   "name": "Your_App_Name",
+//   "license": "__YOUR_NEW_RELIC_LICENSE_KEY__"
 // }
+// enable defaults to true for the staging and prod profiles
 //
 package newrelic
 
@@ -17,6 +18,7 @@ import (
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
 	"github.com/newrelic/go-agent"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"reflect"
 	"runtime"
@@ -24,15 +26,30 @@ import (
 )
 
 var settings = config.AppSettings()
-var log = logging.Logger()
+var log = logging.Named("newrelic")
+
+// SetLogger makes the package log to l instead of the "newrelic" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 type NewRelicEnv struct {
-	Enable  bool   `env:"goboot_newrelic.enable"`
-	Name    string `env:"goboot_newrelic.name"`
-	License string `env:"goboot_newrelic.license"`
+	Enable  bool   `env:"goboot_newrelic.enable" desc:"start the agent, true for the staging and prod profiles"`
+	Name    string `env:"goboot_newrelic.name" desc:"application name reported, the CF application name otherwise"`
+	License string `env:"goboot_newrelic.license" desc:"New Relic license key"`
+}
+
+// String keeps a license key read from a secret out of the logs.
+func (r NewRelicEnv) String() string {
+	return settings.Redact(r)
 }
 
 func init() {
+	config.Declare(NewRelicEnv{}, "New Relic agent")
+
+	settings.SetProfileDefault(config.ProfileStaging, "goboot_newrelic.enable", true)
+	settings.SetProfileDefault(config.ProfileProd, "goboot_newrelic.enable", true)
+
 	env := NewRelicEnv{}
 
 	err := settings.Parse(&env)
@@ -42,7 +59,7 @@ func init() {
 	}
 
 	log.Debugf("NewRelic goboot_newrelic.enable: %v", env.Enable)
-	if !env.Enable {
+	if !env.Enable || config.DeclareOnly() {
 		return
 	}
 
@@ -4,11 +4,17 @@ package util
 import (
 	"fmt"
 	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
 	"math/rand"
 	"time"
 )
 
-var log = logging.Logger()
+var log = logging.Named("util")
+
+// SetLogger makes the package log to l instead of the "util" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 type Operation func() error
 
@@ -20,7 +26,7 @@ func Retry(op Operation, bo ...BackOff) (err error) {
 		b = bo[0]
 	}
 	for i := 0; i < b.attempts; i++ {
-		log.Printf("Retry count:  %d\n", i)
+		log.Debugf("Retry count:  %d\n", i)
 
 		if err = op(); err == nil {
 			return nil
@@ -32,7 +38,7 @@ func Retry(op Operation, bo ...BackOff) (err error) {
 
 		time.Sleep(d)
 
-		log.Printf("Retrying after %s ...\n", d)
+		log.Debugf("Retrying after %s ...\n", d)
 	}
 	return fmt.Errorf("Failed after %d attempts, last error: %s", b.attempts, err)
 }
@@ -4,6 +4,10 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/gostones/goboot/logtest"
+	"github.com/sirupsen/logrus"
 )
 
 func TestRetryDefaultBackOff(t *testing.T) {
@@ -84,3 +88,22 @@ func TestRetryBackOff(t *testing.T) {
 		t.Errorf("Invalid number of retries: %d", i)
 	}
 }
+
+func TestRetryLogged(t *testing.T) {
+	l, rec := logtest.New(t)
+	defer l.Close()
+	SetLogger(l.Entry)
+	defer SetLogger(logging.Named("util"))
+
+	var i = 0
+	Retry(func() error {
+		i++
+		if i == 2 {
+			return nil
+		}
+		return errors.New("some error")
+	}, NewBackOff(2, time.Millisecond))
+
+	rec.AssertLogged(logrus.InfoLevel, "Operation error:  some error", nil)
+	rec.AssertLogged(logrus.DebugLevel, "Retry count:  1", nil)
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gostones/goboot/audit"
+	"github.com/gostones/goboot/config"
+	"github.com/gostones/goboot/logging"
+)
+
+// The admin endpoints are disabled by default. Enable them behind a bearer token with:
+//
+//	goboot_admin={
+//	  "enable": true,
+//	  "token": "file:///run/secrets/admin-token"
+//	}
+//
+//	GET /admin/config   explains the resolved configuration, see config.Settings.Explain
+//	/admin/logging      gets and sets the log levels, see logging.LevelHandler
+//
+// They change the running app, so they are not mounted without a token.
+// Other packages add endpoints with RegisterAdmin, e.g. /admin/flags.
+// Every call is recorded to the audit trail, see audit.Record.
+
+// AdminPath is the prefix the admin endpoints are mounted under.
+const AdminPath = "/admin/"
+
+type AdminEnv struct {
+	Enable bool   `env:"goboot_admin.enable" desc:"serve the admin endpoints under /admin/"`
+	Token  string `env:"goboot_admin.token" desc:"bearer token required by the admin endpoints, which are not served without one"`
+}
+
+func init() {
+	config.Declare(AdminEnv{}, "Admin endpoints")
+	config.Declare(HttpEnv{}, "HTTP middleware")
+
+	RegisterAdmin("logging", logging.LevelHandler())
+}
+
+var admin = struct {
+	handlers map[string]http.Handler
+	sync.Mutex
+}{handlers: map[string]http.Handler{}}
+
+// RegisterAdmin adds h as the admin endpoint name, served at /admin/<name> and below
+// with the prefix stripped, so h sees "" or "/<rest>" as the path.
+func RegisterAdmin(name string, h http.Handler) {
+	admin.Lock()
+	defer admin.Unlock()
+	admin.handlers[name] = h
+}
+
+// ConfigHandler serves the configuration report of config.AppSettings, which the
+// goboot packages and apps parse their settings from.
+func ConfigHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	HandleJson(config.AppSettings().Explain(), res, req)
+}
+
+// AdminHandler returns the admin endpoints, requiring "Authorization: Bearer <token>".
+// Every request is refused if no token is configured.
+func AdminHandler(env AdminEnv) http.Handler {
+	return auditAdmin(env, adminHandler(env))
+}
+
+func adminHandler(env AdminEnv) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(AdminPath+"config", ConfigHandler)
+
+	admin.Lock()
+	for name, h := range admin.handlers {
+		h = http.StripPrefix(AdminPath+name, h)
+		mux.Handle(AdminPath+name, h)
+		mux.Handle(AdminPath+name+"/", h)
+	}
+	admin.Unlock()
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if env.Token == "" {
+			http.Error(res, "admin token not configured", http.StatusForbidden)
+			return
+		}
+		if !validToken(req, env.Token) {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(res, req)
+	})
+}
+
+// MountAdmin adds the admin endpoints, if enabled, with handle, which must route
+// AdminPath and everything below it to the handler, e.g. http.ServeMux.Handle.
+func MountAdmin(handle func(pattern string, h http.Handler)) {
+	env := AdminEnv{}
+	if err := config.AppSettings().Parse(&env); err != nil {
+		log.Errorf("Admin init error: %v", err)
+		return
+	}
+	if env.Enable && env.Token == "" {
+		log.Errorf("Admin endpoints not mounted: goboot_admin.token is not set")
+		return
+	}
+	if env.Enable {
+		handle(AdminPath, AdminHandler(env))
+		log.Infof("Admin endpoints enabled at %s", AdminPath)
+	}
+}
+
+// auditAdmin records the calls of the admin endpoints with their status.
+func auditAdmin(env AdminEnv, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		actor := adminActor(req, env)
+		ctx := audit.NewContext(req.Context(), actor)
+		ctx = logging.NewContext(ctx, logging.FromContext(ctx).WithField(logging.FieldActor, actor))
+		w := newResponseWriter(res)
+		next.ServeHTTP(w, req.WithContext(ctx))
+
+		outcome := audit.OutcomeSuccess
+		if w.Status() >= http.StatusBadRequest {
+			outcome = audit.OutcomeFailure
+		}
+		audit.Record(ctx, audit.Event{
+			Action:  "admin." + strings.ToLower(req.Method),
+			Target:  req.URL.Path,
+			Outcome: outcome,
+			Details: map[string]string{"status": strconv.Itoa(w.Status())},
+		})
+	})
+}
+
+// adminActor identifies the caller by the credential it authenticated with, the admin
+// token or none, and its address.
+func adminActor(req *http.Request, env AdminEnv) string {
+	if env.Token != "" && validToken(req, env.Token) {
+		return "token@" + req.RemoteAddr
+	}
+	return "anonymous@" + req.RemoteAddr
+}
+
+func validToken(req *http.Request, token string) bool {
+	bearer := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
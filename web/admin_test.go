@@ -0,0 +1,98 @@
+package web
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gostones/goboot/audit"
+	"github.com/gostones/goboot/logging"
+	"github.com/gostones/goboot/logtest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// tempAudit records to a temporary file store until cleanup is called.
+func tempAudit(t *testing.T) (audit.Store, func()) {
+	dir, _ := ioutil.TempDir("", "goboot-admin")
+	s, err := audit.NewFileStore(filepath.Join(dir, "audit.log"))
+	assert.NoError(t, err)
+	audit.SetStore(s)
+	return s, func() {
+		audit.SetStore(nil)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	s, cleanup := tempAudit(t)
+	defer cleanup()
+
+	h := RequestID(AdminHandler(AdminEnv{Token: "t0ken"}))
+
+	req := httptest.NewRequest(http.MethodGet, AdminPath+"logging", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.SetBasicAuth("alice", "secret")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, AdminPath+"logging", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var events []audit.Event
+	s.Read(func(e *audit.Event) error {
+		events = append(events, *e)
+		return nil
+	})
+	assert.Len(t, events, 2)
+	assert.Equal(t, "admin.get", events[0].Action)
+	assert.Equal(t, "/admin/logging", events[0].Target)
+	assert.Equal(t, audit.OutcomeFailure, events[0].Outcome)
+	assert.Equal(t, "anonymous@192.0.2.1:1234", events[0].Actor)
+	assert.Equal(t, "req-1", events[0].RequestID)
+	assert.Equal(t, "401", events[0].Details["status"])
+	assert.Equal(t, audit.OutcomeSuccess, events[1].Outcome)
+	assert.Equal(t, "token@192.0.2.1:1234", events[1].Actor)
+}
+
+func TestAdminLevelLog(t *testing.T) {
+	_, cleanup := tempAudit(t)
+	defer cleanup()
+	rec, stop := logtest.Capture(t)
+	defer stop()
+	defer logging.ResetLevel("test/admin")
+
+	h := RequestID(AdminHandler(AdminEnv{Token: "t0ken"}))
+	req := httptest.NewRequest(http.MethodPut, AdminPath+"logging/test/admin", strings.NewReader(`{"level": "warn"}`))
+	req.Header.Set(HeaderRequestID, "req-2")
+	req.Header.Set("Authorization", "Bearer t0ken")
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	rec.AssertLogged(logrus.WarnLevel, "Log level changed", logrus.Fields{
+		logging.FieldActor:     "token@192.0.2.1:1234",
+		logging.FieldRequestID: "req-2",
+		"target":               "test/admin",
+	})
+}
+
+func TestAdminWithoutToken(t *testing.T) {
+	_, cleanup := tempAudit(t)
+	defer cleanup()
+
+	h := AdminHandler(AdminEnv{Enable: true})
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(method, AdminPath+"logging/audit", nil))
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	}
+}
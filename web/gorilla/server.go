@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gostones/goboot/logging"
 	"github.com/gostones/goboot/web"
+	"github.com/sirupsen/logrus"
 	"net/http"
 )
 
@@ -13,7 +14,12 @@ type GorillaServer struct {
 	Router *mux.Router
 }
 
-var log = logging.Logger()
+var log = logging.Named("web/gorilla")
+
+// SetLogger makes the package log to l instead of the "web/gorilla" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 func (r *GorillaServer) Serve() {
 	port := r.Port()
@@ -23,10 +29,13 @@ func (r *GorillaServer) Serve() {
 		r.Router = mux.NewRouter()
 		r.Router.HandleFunc("/", r.home)
 	}
+	web.MountAdmin(func(pattern string, h http.Handler) {
+		r.Router.PathPrefix(pattern).Handler(h)
+	})
 
 	log.Infof("Server listening on port: %s", port)
 
-	log.Fatal(http.ListenAndServe(":"+port, r.Router))
+	log.Fatal(http.ListenAndServe(":"+port, r.Handler(r.Router)))
 }
 
 func (r *GorillaServer) home(res http.ResponseWriter, req *http.Request) {
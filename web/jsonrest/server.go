@@ -4,6 +4,7 @@ import (
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/gostones/goboot/logging"
 	"github.com/gostones/goboot/web"
+	"github.com/sirupsen/logrus"
 	"net/http"
 )
 
@@ -12,15 +13,34 @@ type JsonRestServer struct {
 
 	Api    *rest.Api
 	Router rest.App
+	// Stack is the go-json-rest middleware of the Api, within the chain of the server,
+	// see DefaultStack.
+	Stack []rest.Middleware
 }
 
-var log = logging.Logger()
+// DefaultStack returns the part of rest.DefaultDevStack the chain of the server lacks:
+// the X-Powered-By header, indented JSON and the Content-Type check. Use
+// rest.DefaultDevStack for its access log, timer and recovery as well.
+func DefaultStack() []rest.Middleware {
+	return []rest.Middleware{
+		&rest.PoweredByMiddleware{},
+		&rest.JsonIndentMiddleware{},
+		&rest.ContentTypeCheckerMiddleware{},
+	}
+}
+
+var log = logging.Named("web/jsonrest")
+
+// SetLogger makes the package log to l instead of the "web/jsonrest" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 func (r *JsonRestServer) Serve() {
 	port := r.Port()
 
 	//
-	r.Api.Use(rest.DefaultDevStack...)
+	r.Api.Use(r.Stack...)
 
 	//
 	if r.Router == nil {
@@ -35,9 +55,13 @@ func (r *JsonRestServer) Serve() {
 
 	r.Api.SetApp(r.Router)
 
+	mux := http.NewServeMux()
+	mux.Handle("/", r.Api.MakeHandler())
+	web.MountAdmin(mux.Handle)
+
 	log.Infof("Server listening on port: %s", port)
 
-	log.Fatal(http.ListenAndServe(":"+port, r.Api.MakeHandler()))
+	log.Fatal(http.ListenAndServe(":"+port, r.Handler(mux)))
 }
 
 func HandlerAdapter(handler func(http.ResponseWriter, *http.Request)) rest.HandlerFunc {
@@ -68,8 +92,8 @@ func NewJsonRestServer(router ...rest.App) *JsonRestServer {
 	ctx := web.CreateAppContext()
 
 	if len(router) == 0 {
-		return &JsonRestServer{BasicServer: web.BasicServer{Ctx: ctx}, Api: rest.NewApi(), Router: nil}
+		return &JsonRestServer{BasicServer: web.BasicServer{Ctx: ctx}, Api: rest.NewApi(), Router: nil, Stack: DefaultStack()}
 	} else {
-		return &JsonRestServer{BasicServer: web.BasicServer{Ctx: ctx}, Api: rest.NewApi(), Router: router[0]}
+		return &JsonRestServer{BasicServer: web.BasicServer{Ctx: ctx}, Api: rest.NewApi(), Router: router[0], Stack: DefaultStack()}
 	}
 }
@@ -0,0 +1,303 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gostones/goboot/config"
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// The servers wrap their router, admin endpoints included, with the middleware of
+// goboot_http followed by the one added with Use:
+//
+//	goboot_http={
+//	  "access_log": true,
+//	  "gzip": true,
+//	  "timeout": "30s",
+//	  "cors": {"origins": ["https://app.example.com"], "headers": ["Authorization"]}
+//	}
+//
+// RequestID and Recovery apply even if the settings are invalid, the access log unless disabled.
+
+// Middleware wraps a handler, e.g. to add cross-cutting behavior to every route.
+type Middleware func(http.Handler) http.Handler
+
+type HttpEnv struct {
+	AccessLog   bool          `env:"goboot_http.access_log" envDefault:"true" desc:"log every request with its status and duration"`
+	Gzip        bool          `env:"goboot_http.gzip" desc:"compress responses for clients accepting gzip"`
+	Timeout     time.Duration `env:"goboot_http.timeout" desc:"respond 503 to requests not handled within this duration, e.g. 30s"`
+	CORSOrigins []string      `env:"goboot_http.cors.origins" desc:"origins allowed to make cross-origin requests, * for any, CORS is disabled if empty"`
+	CORSMethods []string      `env:"goboot_http.cors.methods" desc:"methods allowed in cross-origin requests, GET, HEAD and POST otherwise"`
+	CORSHeaders []string      `env:"goboot_http.cors.headers" desc:"request headers allowed in cross-origin requests"`
+	CORSMaxAge  time.Duration `env:"goboot_http.cors.max_age" desc:"how long browsers cache preflight responses, e.g. 10m"`
+}
+
+// Chain wraps h with m, the first middleware outermost.
+func Chain(h http.Handler, m ...Middleware) http.Handler {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	return h
+}
+
+// DefaultStack returns the middleware of goboot_http. RequestID and Recovery are returned
+// even if the settings are invalid.
+func DefaultStack() []Middleware {
+	stack := []Middleware{RequestID, AccessLog, Recovery}
+
+	env := HttpEnv{}
+	if err := config.AppSettings().Parse(&env); err != nil {
+		log.Errorf("HTTP middleware init error: %v", err)
+		return []Middleware{RequestID, Recovery}
+	}
+	if !env.AccessLog {
+		stack = []Middleware{RequestID, Recovery}
+	}
+	if len(env.CORSOrigins) > 0 {
+		stack = append(stack, CORS(CORSOptions{
+			Origins: env.CORSOrigins,
+			Methods: env.CORSMethods,
+			Headers: env.CORSHeaders,
+			MaxAge:  env.CORSMaxAge,
+		}))
+	}
+	if env.Gzip {
+		stack = append(stack, Gzip)
+	}
+	if env.Timeout > 0 {
+		stack = append(stack, Timeout(env.Timeout))
+	}
+	return stack
+}
+
+// Recovery responds 500 to handlers panicking and logs the panic with its stack.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		w := newResponseWriter(res)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			// the server aborts the response silently
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			logging.FromContext(req.Context()).Errorf("Panic serving %s %s: %v\n%s", req.Method, req.URL.Path, v, debug.Stack())
+			if w.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
+
+// AccessLog logs every request with its status, size and duration, with the logger of the
+// request context, see RequestID.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		w := newResponseWriter(res)
+		next.ServeHTTP(w, req)
+
+		logging.FromContext(req.Context()).WithFields(logrus.Fields{
+			"method":      req.Method,
+			"path":        req.URL.Path,
+			"status":      w.Status(),
+			"bytes":       w.bytes,
+			"duration_ms": time.Since(start).Seconds() * 1000,
+			"remote_addr": req.RemoteAddr,
+		}).Infof("%s %s %d", req.Method, req.URL.Path, w.Status())
+	})
+}
+
+// CORSOptions configure CORS.
+type CORSOptions struct {
+	// Origins allowed, * for any.
+	Origins []string
+	// Methods allowed, GET, HEAD and POST if empty.
+	Methods []string
+	// Headers allowed in requests.
+	Headers []string
+	// MaxAge of preflight responses in caches, not sent if 0.
+	MaxAge time.Duration
+}
+
+// CORS allows cross-origin requests from the origins of opts and answers their preflight requests.
+func CORS(opts CORSOptions) Middleware {
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	allowed := func(origin string) bool {
+		for _, o := range opts.Origins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			h := res.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || !allowed(origin) {
+				next.ServeHTTP(res, req)
+				return
+			}
+			h.Set("Access-Control-Allow-Origin", origin)
+
+			if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(res, req)
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			if len(opts.Headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(opts.Headers, ", "))
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+			}
+			res.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// Gzip compresses the responses for clients accepting gzip, unless the handler set
+// a Content-Encoding or the response has no body.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Add("Vary", "Accept-Encoding")
+		if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(res, req)
+			return
+		}
+		w := &gzipWriter{ResponseWriter: res}
+		defer w.Close()
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Timeout responds 503 to requests not handled within d, see http.TimeoutHandler.
+// The handlers should stop when the request context is done.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
+
+// responseWriter keeps the status and the size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseWriter(res http.ResponseWriter) *responseWriter {
+	if w, ok := res.(*responseWriter); ok {
+		return w
+	}
+	return &responseWriter{ResponseWriter: res}
+}
+
+// Status returns the status written, 200 if the handler wrote none.
+func (r *responseWriter) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *responseWriter) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseWriter) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseWriter) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijacking not supported")
+}
+
+// gzipWriter compresses the body once the status is known to allow one.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (r *gzipWriter) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	h := r.Header()
+	if status != http.StatusNoContent && status != http.StatusNotModified && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		r.gz = gzip.NewWriter(r.ResponseWriter)
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *gzipWriter) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		if r.Header().Get("Content-Type") == "" {
+			r.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.gz == nil {
+		return r.ResponseWriter.Write(b)
+	}
+	return r.gz.Write(b)
+}
+
+func (r *gzipWriter) Flush() {
+	if r.gz != nil {
+		r.gz.Flush()
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *gzipWriter) Close() error {
+	if r.gz == nil {
+		return nil
+	}
+	return r.gz.Close()
+}
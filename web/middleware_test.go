@@ -0,0 +1,121 @@
+package web
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gostones/goboot/logtest"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(res, req)
+			})
+		}
+	}
+	s := &BasicServer{}
+	s.Use(mark("a"), mark("b"))
+	h := s.Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	}))
+
+	res := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"a", "b", "handler"}, calls)
+	// the default stack applies
+	assert.NotEmpty(t, res.Header().Get(HeaderRequestID))
+}
+
+func TestRecoveryAccessLog(t *testing.T) {
+	rec, restore := logtest.Capture(t)
+	defer restore()
+
+	h := Chain(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}), RequestID, AccessLog, Recovery)
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	res := serve(h, req)
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	rec.AssertLogged(logrus.ErrorLevel, "Panic serving GET /panic: boom", logrus.Fields{"request_id": "req-1"})
+	rec.AssertLogged(logrus.InfoLevel, "GET /panic 500", logrus.Fields{"request_id": "req-1", "status": 500})
+}
+
+func TestCORS(t *testing.T) {
+	h := CORS(CORSOptions{Origins: []string{"https://app.example.com"}, Headers: []string{"Authorization"}, MaxAge: time.Minute})(
+		http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.Write([]byte("ok"))
+		}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	res := serve(h, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "https://app.example.com", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, POST", res.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", res.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", res.Header().Get("Access-Control-Max-Age"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	res = serve(h, req)
+	assert.Equal(t, "ok", res.Body.String())
+	assert.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat("goboot ", 100)
+	h := Gzip(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/empty" {
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+		res.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	res := serve(h, req)
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+	r, err := gzip.NewReader(res.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, body, string(b))
+
+	req = httptest.NewRequest(http.MethodGet, "/empty", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res = serve(h, req)
+	assert.Empty(t, res.Header().Get("Content-Encoding"))
+	assert.Equal(t, 0, res.Body.Len())
+
+	res = serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, body, res.Body.String())
+}
+
+func TestTimeout(t *testing.T) {
+	h := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	res := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 The Goboot Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers correlating requests.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+	HeaderB3          = "b3"
+	HeaderB3TraceID   = "X-B3-TraceId"
+	HeaderB3SpanID    = "X-B3-SpanId"
+)
+
+// request ids taken from clients are limited to this length
+const maxRequestID = 128
+
+// RequestID adds a logger with the request id to the request context, see logging.FromContext,
+// and echoes the id in the X-Request-ID response header.
+// The id is taken from the X-Request-ID header, the trace id of a W3C traceparent
+// or B3 header, or generated. Trace and parent span ids are logged as well.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fields := requestFields(req)
+		id := fields[logging.FieldRequestID].(string)
+
+		e := logging.FromContext(req.Context()).WithFields(fields)
+		res.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(res, req.WithContext(logging.NewContext(req.Context(), e)))
+	})
+}
+
+func requestFields(req *http.Request) logrus.Fields {
+	fields := logrus.Fields{}
+
+	traceID, spanID := traceparent(req.Header.Get(HeaderTraceparent))
+	if traceID == "" {
+		traceID, spanID = b3(req.Header)
+	}
+	if traceID != "" {
+		fields[logging.FieldTraceID] = traceID
+		fields[logging.FieldSpanID] = spanID
+	}
+
+	id := req.Header.Get(HeaderRequestID)
+	if !validID(id) {
+		id = traceID
+	}
+	if id == "" {
+		id = newID()
+	}
+	fields[logging.FieldRequestID] = id
+	return fields
+}
+
+// traceparent returns the trace and parent ids of a W3C traceparent header,
+// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func traceparent(h string) (traceID, spanID string) {
+	p := strings.Split(strings.TrimSpace(h), "-")
+	if len(p) < 4 || len(p[0]) != 2 || !isHex(p[1], 32) || !isHex(p[2], 16) {
+		return "", ""
+	}
+	if p[1] == strings.Repeat("0", 32) {
+		return "", ""
+	}
+	return p[1], p[2]
+}
+
+// b3 returns the trace and span ids of the single b3 header or the X-B3-* headers.
+func b3(h http.Header) (traceID, spanID string) {
+	if s := h.Get(HeaderB3); s != "" {
+		p := strings.Split(s, "-")
+		if len(p) >= 2 {
+			traceID, spanID = p[0], p[1]
+		}
+	} else {
+		traceID, spanID = h.Get(HeaderB3TraceID), h.Get(HeaderB3SpanID)
+	}
+	if !(isHex(traceID, 16) || isHex(traceID, 32)) || !isHex(spanID, 16) {
+		return "", ""
+	}
+	return traceID, spanID
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// validID accepts printable ASCII ids without spaces so they can't forge log lines.
+func validID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Request id error: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gostones/goboot/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveRequestID(header http.Header) (string, string, *httptest.ResponseRecorder) {
+	var id, trace string
+	h := RequestID(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id = logging.RequestID(req.Context())
+		trace, _ = logging.FromContext(req.Context()).Data[logging.FieldTraceID].(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return id, trace, res
+}
+
+func TestRequestID(t *testing.T) {
+	id, trace, res := serveRequestID(http.Header{"X-Request-Id": {"req-1"}})
+	assert.Equal(t, "req-1", id)
+	assert.Equal(t, "", trace)
+	assert.Equal(t, "req-1", res.Header().Get(HeaderRequestID))
+
+	id, trace, _ = serveRequestID(http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", id)
+	assert.Equal(t, id, trace)
+
+	id, trace, _ = serveRequestID(http.Header{"B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"}})
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", id)
+
+	id, trace, _ = serveRequestID(http.Header{"X-B3-Traceid": {"463ac35c9f6413ad"}, "X-B3-Spanid": {"a2fb4a1d1a96d312"}, "X-Request-Id": {"bad id\n"}})
+	assert.Equal(t, "463ac35c9f6413ad", id)
+	assert.Equal(t, id, trace)
+
+	id, _, res = serveRequestID(http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}})
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, res.Header().Get(HeaderRequestID))
+}
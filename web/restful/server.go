@@ -4,6 +4,7 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/gostones/goboot/logging"
 	"github.com/gostones/goboot/web"
+	"github.com/sirupsen/logrus"
 	"net/http"
 )
 
@@ -13,7 +14,12 @@ type RestfulServer struct {
 	Router *restful.WebService
 }
 
-var log = logging.Logger()
+var log = logging.Named("web/restful")
+
+// SetLogger makes the package log to l instead of the "web/restful" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 func (r *RestfulServer) Serve() {
 	port := r.Port()
@@ -25,10 +31,11 @@ func (r *RestfulServer) Serve() {
 	}
 
 	restful.Add(r.Router)
+	web.MountAdmin(restful.DefaultContainer.Handle)
 
 	log.Infof("Server listening on port: %s", port)
 
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, r.Handler(http.DefaultServeMux)))
 }
 
 func HandlerAdapter(handler func(http.ResponseWriter, *http.Request)) restful.RouteFunction {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/gostones/goboot/config"
 	"github.com/gostones/goboot/logging"
+	"github.com/sirupsen/logrus"
 	"github.com/tylerb/graceful"
 	"net/http"
 	"time"
@@ -22,6 +23,8 @@ type BasicServer struct {
 	Ctx *AppContext
 
 	Router *http.ServeMux
+
+	middleware []Middleware
 }
 
 var ContentType = struct {
@@ -38,7 +41,12 @@ var ContentType = struct {
 	BIN:  "application/octet-stream",
 }
 
-var log = logging.Logger()
+var log = logging.Named("web")
+
+// SetLogger makes the package log to l instead of the "web" logger.
+func SetLogger(l *logrus.Entry) {
+	log = l
+}
 
 func CreateAppContext() *AppContext {
 	p := config.NewSettings()
@@ -61,11 +69,24 @@ func (r *BasicServer) Port() string {
 	return port
 }
 
+// Use adds middleware applied to every request after the DefaultStack, in order.
+// Call it before Serve.
+func (r *BasicServer) Use(m ...Middleware) {
+	r.middleware = append(r.middleware, m...)
+}
+
+// Handler wraps h with the DefaultStack and the middleware added with Use.
+// The servers call it with their router.
+func (r *BasicServer) Handler(h http.Handler) http.Handler {
+	return Chain(Chain(h, r.middleware...), DefaultStack()...)
+}
+
 func (r *BasicServer) Serve() {
 	if r.Router == nil {
 		r.Router = http.NewServeMux()
 		r.Router.HandleFunc("/", r.home)
 	}
+	MountAdmin(r.Router.Handle)
 
 	r.Start()
 }
@@ -77,7 +98,7 @@ func (r *BasicServer) Start() {
 		Timeout: 10 * time.Second,
 		Server: &http.Server{
 			Addr:    ":" + port,
-			Handler: r.Router,
+			Handler: r.Handler(r.Router),
 		},
 	}
 
@@ -132,7 +153,11 @@ func NewBasicServer(router ...*http.ServeMux) *BasicServer {
 	}
 }
 
+// Run serves s, or a BasicServer. In strict mode the goboot_* settings are checked
+// for unknown keys first, see config.Settings.CheckKeys.
 func Run(s ...Server) {
+	checkKeys()
+
 	if len(s) == 0 {
 		bs := NewBasicServer()
 		bs.Serve()
@@ -142,3 +167,16 @@ func Run(s ...Server) {
 
 	log.Error("Server exiting.")
 }
+
+func checkKeys() {
+	settings := config.AppSettings()
+	if settings.Strict() == config.StrictOff {
+		return
+	}
+	if err := settings.CheckKeys(); err != nil {
+		if settings.Strict() == config.StrictFail {
+			log.Fatal(err)
+		}
+		log.Warn(err)
+	}
+}
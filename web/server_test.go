@@ -0,0 +1,24 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/gostones/goboot/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckKeys(t *testing.T) {
+	s := config.NewSettings(config.NewMapSource(config.SourceFile, map[string]interface{}{
+		"goboot_logging": map[string]interface{}{"level": "debug", "levels": map[string]interface{}{"audit": "info"}},
+		"goboot_http":    map[string]interface{}{"gzip": true},
+		"goboot_admin":   map[string]interface{}{"enable": false},
+		"goboot_audit":   map[string]interface{}{"store": "file"},
+	}))
+	s.SetStrict(config.StrictFail)
+	assert.NoError(t, s.CheckKeys())
+
+	s = config.NewSettings(config.NewMapSource(config.SourceFile, map[string]interface{}{
+		"goboot_logging": map[string]interface{}{"levle": "debug"},
+	}))
+	assert.EqualError(t, s.CheckKeys(), "goboot_logging.levle from file: unknown key, did you mean goboot_logging.level?")
+}